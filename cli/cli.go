@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// errUsage is returned by commands called with the wrong arguments, the usage
+// for the command has already been printed when it's returned.
+var errUsage = errors.New("invalid usage")
+
+// errChanges is returned by commands that succeeded but should still exit with
+// a non-zero status code, like a diff that found differences.
+var errChanges = errors.New("changes found")
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string) error
+}
+
+var commands []command
+
+// Run runs the command from the arguments and returns the exit code, the
+// server is started when no command is given.
+func Run(args []string, serve func()) int {
+	commands = []command{
+		{
+			name:        "serve",
+			description: "Starts the jobs and the HTTP server (the default)",
+			run: func(args []string) error {
+				checkEnvironment()
+				serve()
+
+				return nil
+			},
+		},
+		{
+			name:        "fetch",
+			usage:       "[-concurrency n] [-strict]",
+			description: "Updates every source and internal plugin once and writes the cache",
+			run:         runFetch,
+		},
+		{
+			name:        "validate",
+			usage:       "<file|url>",
+			description: "Loads a repository manifest and reports its issues",
+			run:         runValidate,
+		},
+		{
+			name:        "export",
+			usage:       "[-pretty]",
+			description: "Prints the cached plugin feed as JSON",
+			run:         runExport,
+		},
+		{
+			name:        "diff",
+			usage:       "<old> <new>",
+			description: "Compares the plugins in two repository cache files",
+			run:         runDiff,
+		},
+	}
+
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		err := cmd.run(args)
+
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errChanges):
+			return 1
+		case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+			return 2
+		}
+
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+
+	fmt.Fprintf(stderr, "Unknown command %q\n\n", name)
+	printUsage()
+
+	return 2
+}
+
+func printUsage() {
+	fmt.Fprintln(stderr, "Usage: dalamud-plugin-listing <command> [arguments]")
+	fmt.Fprintln(stderr)
+	fmt.Fprintln(stderr, "Commands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(stderr, "  %-10s %-28s %s\n", cmd.name, cmd.usage, cmd.description)
+	}
+}
+
+// newFlagSet creates the flag set for a command, it prints the usage of the
+// command when the arguments can't be parsed.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	flags.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				fmt.Fprintf(stderr, "Usage: dalamud-plugin-listing %s %s\n", cmd.name, cmd.usage)
+			}
+		}
+
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func checkEnvironment() {
+	if os.Getenv("APP_URL") == "" {
+		slog.Warn("APP_URL environment variable is not set, defaulting to http://localhost:8080/")
+		os.Setenv("APP_URL", "http://localhost:8080/")
+	}
+
+	if os.Getenv("GITHUB_TOKEN") == "" {
+		slog.Warn("GITHUB_TOKEN environment variable is not set, some features may not work properly")
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var output bytes.Buffer
+	previousOut, previousErr := stdout, stderr
+	stdout, stderr = &output, &output
+
+	t.Cleanup(func() {
+		stdout, stderr = previousOut, previousErr
+	})
+
+	return &output
+}
+
+func TestDiffRepositories(t *testing.T) {
+	oldRepos := []state.Repository{
+		{Author: "Senither", InternalName: "Removed", AssemblyVersion: "1.0.0.0"},
+		{Author: "Senither", InternalName: "Changed", AssemblyVersion: "1.0.0.0", DalamudApiLevel: 12},
+		{Author: "Senither", InternalName: "Same", AssemblyVersion: "1.0.0.0"},
+	}
+
+	newRepos := []state.Repository{
+		{Author: "Senither", InternalName: "Changed", AssemblyVersion: "1.1.0.0", DalamudApiLevel: 12},
+		{Author: "Senither", InternalName: "Same", AssemblyVersion: "1.0.0.0"},
+		{Author: "Senither", InternalName: "Added", AssemblyVersion: "2.0.0.0"},
+	}
+
+	changes := diffRepositories(oldRepos, newRepos)
+
+	expected := []struct{ kind, key string }{
+		{"+", "Senither/Added"},
+		{"~", "Senither/Changed"},
+		{"-", "Senither/Removed"},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+
+	for i, want := range expected {
+		if changes[i].kind != want.kind || changes[i].key != want.key {
+			t.Errorf("Expected %s %s, got %+v", want.kind, want.key, changes[i])
+		}
+	}
+
+	if details := changes[1].details; len(details) != 1 || !strings.Contains(details[0], `"1.0.0.0" -> "1.1.0.0"`) {
+		t.Errorf("Expected the version change to be described, got %v", details)
+	}
+}
+
+func TestValidateReportsRejectedEntries(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("APP_CONFIG", "")
+
+	manifest := `[
+		{"Author": "Senither", "Name": "Valid", "InternalName": "Valid", "Description": "A plugin.", "AssemblyVersion": "1.0.0.0", "DalamudApiLevel": 12, "DownloadLinkInstall": "https://example.com/latest.zip"},
+		{"Author": "Senither", "Name": "Broken", "Description": "A plugin.", "AssemblyVersion": "1.0.0.0", "DalamudApiLevel": 12, "DownloadLinkInstall": "https://example.com/latest.zip"},
+	]`
+
+	path := filepath.Join(t.TempDir(), "repo.json")
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureOutput(t)
+
+	if code := Run([]string{"validate", path}, nil); code != 1 {
+		t.Errorf("Expected exit code 1 for a manifest with rejected entries, got %d", code)
+	}
+
+	if !strings.Contains(output.String(), "#1 (no InternalName): rejected") {
+		t.Errorf("Expected the rejected entry to be reported, got:\n%s", output)
+	}
+
+	if !strings.Contains(output.String(), "2 plugins in") || !strings.Contains(output.String(), "1 accepted, 1 rejected") {
+		t.Errorf("Expected a summary of the manifest, got:\n%s", output)
+	}
+}
+
+func TestExportAppliesTheConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("APP_CACHE_DIR", dir)
+	t.Setenv("APP_STORE", "")
+
+	cached := `[
+		{"Author": "Senither", "Name": "Kept", "InternalName": "Kept", "AssemblyVersion": "1.0.0.0", "DalamudApiLevel": 12, "OriginRepositoryUrl": {"RepositoryUrl": "https://example.com/repo.json"}},
+		{"Author": "Senither", "Name": "Denied", "InternalName": "Denied", "AssemblyVersion": "1.0.0.0", "DalamudApiLevel": 12, "OriginRepositoryUrl": {"RepositoryUrl": "https://example.com/repo.json"}},
+		{"Author": "Senither", "Name": "Outdated", "InternalName": "Outdated", "AssemblyVersion": "1.0.0.0", "DalamudApiLevel": 11, "OriginRepositoryUrl": {"RepositoryUrl": "https://example.com/repo.json"}}
+	]`
+
+	cfg := `
+global:
+  api_level:
+    outdated: hide
+sources:
+  - url: https://example.com/repo.json
+overrides:
+  - internal_name: Kept
+    name: Renamed
+policy:
+  rules:
+    - action: deny
+      internal_name: Denied
+`
+
+	if err := os.WriteFile(filepath.Join(dir, "cached-repositories.json"), []byte(cached), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(configPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_CONFIG", configPath)
+
+	output := captureOutput(t)
+
+	if code := Run([]string{"export"}, nil); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s", code, output)
+	}
+
+	var exported []state.Repository
+	if err := json.Unmarshal(output.Bytes(), &exported); err != nil {
+		t.Fatalf("Failed to decode the export: %v\n%s", err, output)
+	}
+
+	if len(exported) != 1 || exported[0].Name != "Renamed" {
+		t.Errorf("Expected only the overridden plugin to be exported, got %+v", exported)
+	}
+}
+
+func TestRunRejectsUnknownCommands(t *testing.T) {
+	output := captureOutput(t)
+
+	if code := Run([]string{"launch"}, nil); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown command, got %d", code)
+	}
+
+	if !strings.Contains(output.String(), "Unknown command \"launch\"") {
+		t.Errorf("Expected the usage to be printed, got:\n%s", output)
+	}
+
+	if code := Run([]string{"diff", "only-one-file"}, nil); code != 2 {
+		t.Errorf("Expected exit code 2 for missing arguments, got %d", code)
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// pluginChange is a difference found for a single plugin between two cache
+// files, the kind is "+" for added, "-" for removed and "~" for changed.
+type pluginChange struct {
+	kind    string
+	key     string
+	details []string
+}
+
+func runDiff(args []string) error {
+	flags := newFlagSet("diff")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errUsage
+	}
+
+	oldRepos, err := state.ReadRepositoriesFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", flags.Arg(0), err)
+	}
+
+	newRepos, err := state.ReadRepositoriesFile(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", flags.Arg(1), err)
+	}
+
+	changes := diffRepositories(oldRepos, newRepos)
+
+	for _, change := range changes {
+		fmt.Fprintf(stdout, "%s %s\n", change.kind, change.key)
+
+		for _, detail := range change.details {
+			fmt.Fprintf(stdout, "    %s\n", detail)
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(stdout, "No differences found")
+		return nil
+	}
+
+	return errChanges
+}
+
+// diffRepositories compares the plugins by author and InternalName, returning
+// the changes sorted by plugin.
+func diffRepositories(oldRepos []state.Repository, newRepos []state.Repository) []pluginChange {
+	oldByKey := indexRepositories(oldRepos)
+	newByKey := indexRepositories(newRepos)
+
+	keys := slices.Collect(maps.Keys(oldByKey))
+	for key := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	var changes []pluginChange
+	for _, key := range keys {
+		before, hadBefore := oldByKey[key]
+		after, hasAfter := newByKey[key]
+
+		switch {
+		case !hadBefore:
+			changes = append(changes, pluginChange{kind: "+", key: key, details: describeRepository(after)})
+		case !hasAfter:
+			changes = append(changes, pluginChange{kind: "-", key: key, details: describeRepository(before)})
+		default:
+			if details := compareRepositories(before, after); len(details) > 0 {
+				changes = append(changes, pluginChange{kind: "~", key: key, details: details})
+			}
+		}
+	}
+
+	return changes
+}
+
+func indexRepositories(repos []state.Repository) map[string]state.Repository {
+	index := make(map[string]state.Repository, len(repos))
+	for _, repo := range repos {
+		index[repo.Author+"/"+repo.InternalName] = repo
+	}
+
+	return index
+}
+
+func describeRepository(repo state.Repository) []string {
+	return []string{
+		"version: " + repo.AssemblyVersion.String(),
+		fmt.Sprintf("api level: %d", repo.DalamudApiLevel),
+		"origin: " + repo.RepositoryOrigin.RepositoryUrl,
+	}
+}
+
+func compareRepositories(before state.Repository, after state.Repository) []string {
+	fields := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{"version", before.AssemblyVersion.String(), after.AssemblyVersion.String()},
+		{"testing version", before.TestingAssemblyVersion.String(), after.TestingAssemblyVersion.String()},
+		{"api level", fmt.Sprint(before.DalamudApiLevel), fmt.Sprint(after.DalamudApiLevel)},
+		{"download link", stringValue(before.DownloadLinkInstall), stringValue(after.DownloadLinkInstall)},
+		{"tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", ")},
+		{"origin", before.RepositoryOrigin.RepositoryUrl, after.RepositoryOrigin.RepositoryUrl},
+	}
+
+	var details []string
+	for _, field := range fields {
+		if field.before != field.after {
+			details = append(details, fmt.Sprintf("%s: %q -> %q", field.name, field.before, field.after))
+		}
+	}
+
+	return details
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
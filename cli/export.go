@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/senither/dalamud-plugin-listing/cron"
+	"github.com/senither/dalamud-plugin-listing/http/routes"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func runExport(args []string) error {
+	flags := newFlagSet("export")
+	pretty := flags.Bool("pretty", false, "indent the JSON output")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// The config is loaded like the server does, so the overrides, policy and
+	// API level settings are applied to the exported plugins.
+	if err := cron.LoadState(); err != nil {
+		return err
+	}
+
+	defer state.CloseStore()
+
+	encoder := json.NewEncoder(stdout)
+	if *pretty {
+		encoder.SetIndent("", "  ")
+	}
+
+	if err := encoder.Encode(routes.ListPlugins(routes.Filter{})); err != nil {
+		return fmt.Errorf("failed to encode the plugins: %w", err)
+	}
+
+	return nil
+}
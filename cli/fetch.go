@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/senither/dalamud-plugin-listing/cron"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func runFetch(args []string) error {
+	flags := newFlagSet("fetch")
+	concurrency := flags.Int("concurrency", 8, "the number of sources and plugins to update at the same time")
+	strict := flags.Bool("strict", false, "exit with a non-zero status code if any update fails")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	checkEnvironment()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failures, err := cron.FetchOnce(ctx, *concurrency)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(failures)) {
+		fmt.Fprintf(stdout, "FAIL %s: %v\n", key, failures[key])
+	}
+
+	fmt.Fprintf(stdout, "Updated %d sources and %d internal plugins, %d failed, %d plugins cached\n",
+		state.GetUrlsSize(),
+		state.GetInternalPluginSize(),
+		len(failures),
+		state.GetRepositoriesSize(),
+	)
+
+	if *strict && len(failures) > 0 {
+		return fmt.Errorf("%d updates failed", len(failures))
+	}
+
+	return nil
+}
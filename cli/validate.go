@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/cron/jobs"
+	"github.com/senither/dalamud-plugin-listing/validation"
+)
+
+func runValidate(args []string) error {
+	flags := newFlagSet("validate")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	target := flags.Arg(0)
+
+	// The config is optional, it's only used for the validation settings and
+	// the headers sent to a source.
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Warn("Failed to load the config, using the default settings", "err", err)
+		cfg = config.Default()
+	}
+
+	body, err := openManifest(target, cfg.Source(target))
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	repos, size, warnings, err := jobs.DecodeJsonRequestBody(body)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", target, err)
+	}
+
+	accepted, results := validation.Validate(repos, cfg.Source(target).ValidationOptions(cfg.Global))
+
+	for _, warning := range warnings {
+		fmt.Fprintf(stdout, "WARN %s\n", warning)
+	}
+
+	for _, result := range results {
+		status := "ok"
+		if result.Rejected {
+			status = "rejected"
+		}
+
+		name := result.InternalName
+		if name == "" {
+			name = "(no InternalName)"
+		}
+
+		fmt.Fprintf(stdout, "#%d %s: %s\n", result.Index, name, status)
+
+		for _, issue := range result.Issues {
+			fmt.Fprintf(stdout, "  %-7s %s: %s\n", issue.Severity, issue.Rule, issue.Message)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d plugins in %d bytes, %d accepted, %d rejected, %d warnings\n",
+		len(repos),
+		size,
+		len(accepted),
+		len(repos)-len(accepted),
+		len(warnings),
+	)
+
+	if len(accepted) < len(repos) || len(warnings) > 0 {
+		return errChanges
+	}
+
+	return nil
+}
+
+func openManifest(target string, source config.Source) (io.ReadCloser, error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return os.Open(target)
+	}
+
+	client := http.Client{Timeout: time.Minute}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
+
+	for key, value := range source.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,643 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/policy"
+	"github.com/senither/dalamud-plugin-listing/state"
+	"github.com/senither/dalamud-plugin-listing/validation"
+	"gopkg.in/yaml.v3"
+)
+
+const configPathEnv = "APP_CONFIG"
+
+// Trust levels that can be assigned to a source, the entries from trusted
+// sources are only rejected for errors while the entries from untrusted
+// sources are rejected for warnings as well, unless the source sets its own
+// reject severity.
+const (
+	TrustTrusted   = "trusted"
+	TrustCommunity = "community"
+	TrustUntrusted = "untrusted"
+)
+
+// Policies for the plugins from a source that keeps failing, "keep" serves
+// the last known plugins, "degrade" serves them marked as degraded, and
+// "remove" drops them from the listing until the source recovers.
+const (
+	FailurePolicyKeep    = "keep"
+	FailurePolicyDegrade = "degrade"
+	FailurePolicyRemove  = "remove"
+)
+
+// How plugins built for an older or newer Dalamud API level are handled, they
+// can be shown along with the other plugins, hidden, or listed on their own.
+const (
+	OutdatedShow = "show"
+	OutdatedHide = "hide"
+	OutdatedOnly = "only"
+)
+
+// Channels that internal plugins can follow, "latest" uses the newest release
+// while "stable" skips drafts and pre-releases.
+const (
+	ChannelLatest = "latest"
+	ChannelStable = "stable"
+)
+
+type Config struct {
+	Global  Global   `yaml:"global"`
+	Sources []Source `yaml:"sources"`
+	Plugins []Plugin `yaml:"plugins"`
+	// Overrides patch the plugins from sources before they're served.
+	Overrides []Override `yaml:"overrides"`
+	Policy    Policy     `yaml:"policy"`
+	Feeds     []Feed     `yaml:"feeds"`
+}
+
+// Policy decides which plugins from sources are served, the first rule that
+// matches a plugin decides and plugins that match no rule get the default
+// action. Internal plugins are always served.
+type Policy struct {
+	Default string       `yaml:"default"`
+	Rules   []PolicyRule `yaml:"rules"`
+}
+
+type PolicyRule struct {
+	Action       string `yaml:"action"`
+	InternalName string `yaml:"internal_name"`
+	Author       string `yaml:"author"`
+	Source       string `yaml:"source"`
+	Tag          string `yaml:"tag"`
+	// Pattern is a regular expression matched against the InternalName and
+	// the Name of the plugin.
+	Pattern string `yaml:"pattern"`
+	Reason  string `yaml:"reason"`
+}
+
+type Global struct {
+	SourceInterval      IntervalRange `yaml:"source_interval"`
+	PluginInterval      Duration      `yaml:"plugin_interval"`
+	ExpireAfter         Duration      `yaml:"expire_after"`
+	ExpireCheckInterval Duration      `yaml:"expire_check_interval"`
+	FailureBackoff      Backoff       `yaml:"failure_backoff"`
+	FailureThreshold    int           `yaml:"failure_threshold"`
+	FailurePolicy       string        `yaml:"failure_policy"`
+	Validation          Validation    `yaml:"validation"`
+	ApiLevel            ApiLevel      `yaml:"api_level"`
+}
+
+// ApiLevel decides which Dalamud API level is the latest, plugins built for
+// another level are marked as outdated. The configured level is used if it's
+// set, then the level read from the upstream source, and otherwise the
+// highest level any plugin is built for.
+type ApiLevel struct {
+	Latest int `yaml:"latest"`
+	// Source is a Dalamud version info URL the latest level is read from.
+	Source   string   `yaml:"source"`
+	Interval Duration `yaml:"interval"`
+	// Outdated is the default for the plugin feeds, either show or hide.
+	Outdated string `yaml:"outdated"`
+}
+
+// Validation controls which plugin entries from sources are rejected, entries
+// with an issue at or above the reject severity are left out of the listing.
+type Validation struct {
+	Reject string `yaml:"reject"`
+	// Rules overrides the severity of a rule, or turns it off.
+	Rules map[string]string `yaml:"rules"`
+}
+
+// Backoff is the delay before a failing source is fetched again, the delay
+// doubles with every failure in a row up to the max delay.
+type Backoff struct {
+	Base Duration `yaml:"base"`
+	Max  Duration `yaml:"max"`
+}
+
+// IntervalRange is a range a random interval is picked from, this spreads
+// the source updates out so they don't all run at the same time.
+type IntervalRange struct {
+	Min Duration `yaml:"min"`
+	Max Duration `yaml:"max"`
+}
+
+type Source struct {
+	Url      string            `yaml:"url"`
+	Name     string            `yaml:"name"`
+	Enabled  *bool             `yaml:"enabled"`
+	Interval Duration          `yaml:"interval"`
+	Trust    string            `yaml:"trust"`
+	Headers  map[string]string `yaml:"headers"`
+	Tags     TagOverrides      `yaml:"tags"`
+	// OnFailure overrides the global failure policy for the source.
+	OnFailure string `yaml:"on_failure"`
+	// Reject overrides the global validation reject severity for the source.
+	Reject string `yaml:"reject"`
+	// Priority decides which source wins when several sources provide the
+	// same plugin, the highest priority wins.
+	Priority int `yaml:"priority"`
+}
+
+// TagOverrides adds and removes tags on every plugin from a source.
+type TagOverrides struct {
+	Add    []string `yaml:"add,omitempty"`
+	Remove []string `yaml:"remove,omitempty"`
+}
+
+type Plugin struct {
+	Name          string   `yaml:"name"`
+	Private       bool     `yaml:"private"`
+	Enabled       *bool    `yaml:"enabled"`
+	Interval      Duration `yaml:"interval"`
+	ManifestAsset string   `yaml:"manifest_asset"`
+	ReleaseAsset  string   `yaml:"release_asset"`
+	Channel       string   `yaml:"channel"`
+}
+
+var (
+	mu      sync.RWMutex
+	current = Default()
+)
+
+// Get returns the active configuration, it must be treated as read-only.
+func Get() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current
+}
+
+func Set(cfg *Config) {
+	mu.Lock()
+	current = cfg
+	mu.Unlock()
+
+	state.SetSourcePriority(func(url string) int {
+		return cfg.Source(url).Priority
+	})
+
+	state.SetRepositoryOverride(cfg.ApplyOverrides)
+	state.SetConfiguredDalamudApiLevel(state.ApiLevel(cfg.Global.ApiLevel.Latest))
+
+	compiled, err := cfg.Policy.Compile()
+	if err != nil {
+		slog.Error("Failed to compile the policy, every plugin is allowed", "err", err)
+		compiled, _ = policy.New(policy.ActionAllow, nil)
+	}
+
+	state.SetRepositoryPolicy(compiled.Evaluate)
+}
+
+func Default() *Config {
+	return &Config{
+		Global: Global{
+			SourceInterval: IntervalRange{
+				Min: Duration(55 * time.Minute),
+				Max: Duration(70 * time.Minute),
+			},
+			PluginInterval:      Duration(12 * time.Hour),
+			ExpireAfter:         Duration(3 * 24 * time.Hour),
+			ExpireCheckInterval: Duration(30 * time.Second),
+			FailureBackoff: Backoff{
+				Base: Duration(15 * time.Minute),
+				Max:  Duration(24 * time.Hour),
+			},
+			FailureThreshold: 5,
+			FailurePolicy:    FailurePolicyKeep,
+			Validation: Validation{
+				Reject: validation.SeverityError,
+			},
+			ApiLevel: ApiLevel{
+				Interval: Duration(6 * time.Hour),
+				Outdated: OutdatedShow,
+			},
+		},
+	}
+}
+
+// Path returns the path to the config file, set using APP_CONFIG.
+func Path() string {
+	if path := strings.TrimSpace(os.Getenv(configPathEnv)); path != "" {
+		return path
+	}
+
+	return "config.yml"
+}
+
+// Load reads the config file and merges in the sources and plugins from the
+// legacy repositories.txt and plugins.txt files, entries that are already in
+// the config file keep the settings from the config file.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	content, err := os.ReadFile(Path())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := yaml.Unmarshal(content, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", Path(), err)
+		}
+	}
+
+	configExists := err == nil
+
+	legacyExists, err := cfg.mergeLegacyFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if !configExists && !legacyExists {
+		return nil, fmt.Errorf("no sources found, create %s or repositories.txt and plugins.txt", Path())
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Source returns the settings for the given source URL, sources that aren't
+// in the config are returned with the default settings.
+func (c *Config) Source(url string) Source {
+	for _, source := range c.Sources {
+		if source.Url == url {
+			return source
+		}
+	}
+
+	return Source{Url: url}
+}
+
+func (c *Config) Plugin(name string) Plugin {
+	for _, plugin := range c.Plugins {
+		if ip, ok := plugin.InternalPlugin(); ok && strings.EqualFold(ip.Name, name) {
+			return plugin
+		}
+	}
+
+	return Plugin{Name: name}
+}
+
+func (c *Config) EnabledSources() []Source {
+	var sources []Source
+	for _, source := range c.Sources {
+		if source.IsEnabled() {
+			sources = append(sources, source)
+		}
+	}
+
+	return sources
+}
+
+func (c *Config) EnabledPlugins() []Plugin {
+	var plugins []Plugin
+	for _, plugin := range c.Plugins {
+		if plugin.IsEnabled() {
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	return plugins
+}
+
+func (s Source) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+func (s Source) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return s.Url
+}
+
+func (s Source) TrustLevel() string {
+	if s.Trust == "" {
+		return TrustCommunity
+	}
+
+	return s.Trust
+}
+
+// FailurePolicy returns the policy for the plugins from the source once its
+// circuit breaker opens.
+func (s Source) FailurePolicy(global Global) string {
+	if s.OnFailure != "" {
+		return s.OnFailure
+	}
+
+	return global.FailurePolicy
+}
+
+// ValidationOptions returns the options used to validate the plugin entries
+// from the source.
+func (s Source) ValidationOptions(global Global) validation.Options {
+	reject := global.Validation.Reject
+
+	switch {
+	case s.Reject != "":
+		reject = s.Reject
+	case s.TrustLevel() == TrustTrusted && validation.Rejects(validation.SeverityWarning, reject):
+		reject = validation.SeverityError
+	case s.TrustLevel() == TrustUntrusted && !validation.Rejects(validation.SeverityWarning, reject):
+		reject = validation.SeverityWarning
+	}
+
+	return validation.Options{
+		Reject:     reject,
+		Severities: global.Validation.Rules,
+	}
+}
+
+// Compile converts the policy into the rules evaluated for every plugin.
+func (p Policy) Compile() (*policy.Policy, error) {
+	rules := make([]policy.Rule, len(p.Rules))
+	for i, rule := range p.Rules {
+		rules[i] = policy.Rule{
+			Action:       rule.Action,
+			InternalName: rule.InternalName,
+			Author:       rule.Author,
+			Source:       rule.Source,
+			Tag:          rule.Tag,
+			Pattern:      rule.Pattern,
+			Reason:       rule.Reason,
+		}
+	}
+
+	return policy.New(p.Default, rules)
+}
+
+// NextInterval returns the interval for the source, sources without their
+// own interval get a random interval within the global range.
+func (s Source) NextInterval(global Global) time.Duration {
+	if s.Interval > 0 {
+		return s.Interval.Duration()
+	}
+
+	spread := global.SourceInterval.Max - global.SourceInterval.Min
+	if spread <= 0 {
+		return global.SourceInterval.Min.Duration()
+	}
+
+	return global.SourceInterval.Min.Duration() + rand.N(spread.Duration())
+}
+
+// ApplyTags returns the tags with the source tag overrides applied.
+func (s Source) ApplyTags(tags []string) []string {
+	return s.Tags.Apply(tags)
+}
+
+// Apply returns the tags with the removed tags left out and the added tags
+// appended, tags are compared case-insensitively.
+func (t TagOverrides) Apply(tags []string) []string {
+	if len(t.Add) == 0 && len(t.Remove) == 0 {
+		return tags
+	}
+
+	var result []string
+	for _, tag := range tags {
+		if !containsFold(t.Remove, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	for _, tag := range t.Add {
+		if !containsFold(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+func (p Plugin) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// InternalPlugin converts the plugin settings into the internal plugin used
+// by the state, the name uses the same syntax as plugins.txt.
+func (p Plugin) InternalPlugin() (state.InternalPlugin, bool) {
+	ip, ok := state.ParseInternalPlugin(p.Name)
+	if !ok {
+		return ip, false
+	}
+
+	ip.Private = ip.Private || p.Private
+	ip.ManifestAsset = p.ManifestAsset
+	ip.ReleaseAsset = p.ReleaseAsset
+	ip.StableOnly = p.Channel == ChannelStable
+
+	return ip, true
+}
+
+func (p Plugin) NextInterval(global Global) time.Duration {
+	if p.Interval > 0 {
+		return p.Interval.Duration()
+	}
+
+	return global.PluginInterval.Duration()
+}
+
+func (c *Config) mergeLegacyFiles() (bool, error) {
+	repositoriesExist, err := readLegacyFile("repositories.txt", func(line string) {
+		if !slices.ContainsFunc(c.Sources, func(s Source) bool { return s.Url == line }) {
+			c.Sources = append(c.Sources, Source{Url: line})
+		}
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	pluginsExist, err := readLegacyFile("plugins.txt", func(line string) {
+		ip, ok := state.ParseInternalPlugin(line)
+		if !ok {
+			return
+		}
+
+		if !slices.ContainsFunc(c.Plugins, func(p Plugin) bool {
+			existing, ok := p.InternalPlugin()
+			return ok && strings.EqualFold(existing.Name, ip.Name)
+		}) {
+			c.Plugins = append(c.Plugins, Plugin{Name: line})
+		}
+	})
+
+	return repositoriesExist || pluginsExist, err
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	if c.Global.SourceInterval.Min <= 0 || c.Global.SourceInterval.Max < c.Global.SourceInterval.Min {
+		errs = append(errs, fmt.Errorf("global.source_interval must have a positive min that is lower than max"))
+	}
+
+	if c.Global.PluginInterval <= 0 || c.Global.ExpireAfter <= 0 || c.Global.ExpireCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("global intervals must be positive durations"))
+	}
+
+	if c.Global.FailureBackoff.Base <= 0 || c.Global.FailureBackoff.Max < c.Global.FailureBackoff.Base {
+		errs = append(errs, fmt.Errorf("global.failure_backoff must have a positive base that is lower than max"))
+	}
+
+	if c.Global.FailureThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("global.failure_threshold must be a positive number"))
+	}
+
+	if !isFailurePolicy(c.Global.FailurePolicy) {
+		errs = append(errs, fmt.Errorf("global.failure_policy has an unknown policy %q", c.Global.FailurePolicy))
+	}
+
+	if !isRejectSeverity(c.Global.Validation.Reject) {
+		errs = append(errs, fmt.Errorf("global.validation.reject has an unknown severity %q", c.Global.Validation.Reject))
+	}
+
+	if c.Global.ApiLevel.Latest < 0 {
+		errs = append(errs, fmt.Errorf("global.api_level.latest must be a positive number"))
+	}
+
+	if c.Global.ApiLevel.Source != "" && !state.IsValidUrl(c.Global.ApiLevel.Source) {
+		errs = append(errs, fmt.Errorf("global.api_level.source is not a valid URL"))
+	}
+
+	if c.Global.ApiLevel.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("global.api_level.interval must be a positive duration"))
+	}
+
+	if c.Global.ApiLevel.Outdated != OutdatedShow && c.Global.ApiLevel.Outdated != OutdatedHide {
+		errs = append(errs, fmt.Errorf("global.api_level.outdated must be show or hide, got %q", c.Global.ApiLevel.Outdated))
+	}
+
+	for name, severity := range c.Global.Validation.Rules {
+		if !validation.IsRule(name) {
+			errs = append(errs, fmt.Errorf("global.validation.rules has an unknown rule %q, expected one of %s", name, strings.Join(validation.RuleNames(), ", ")))
+		}
+
+		if severity != validation.SeverityOff && !validation.IsSeverity(severity) {
+			errs = append(errs, fmt.Errorf("global.validation.rules.%s has an unknown severity %q", name, severity))
+		}
+	}
+
+	for _, source := range c.Sources {
+		switch source.TrustLevel() {
+		case TrustTrusted, TrustCommunity, TrustUntrusted:
+		default:
+			errs = append(errs, fmt.Errorf("source %s has an unknown trust level %q", source.Url, source.Trust))
+		}
+
+		if source.OnFailure != "" && !isFailurePolicy(source.OnFailure) {
+			errs = append(errs, fmt.Errorf("source %s has an unknown failure policy %q", source.Url, source.OnFailure))
+		}
+
+		if source.Reject != "" && !isRejectSeverity(source.Reject) {
+			errs = append(errs, fmt.Errorf("source %s has an unknown reject severity %q", source.Url, source.Reject))
+		}
+	}
+
+	for _, plugin := range c.Plugins {
+		if _, ok := plugin.InternalPlugin(); !ok {
+			errs = append(errs, fmt.Errorf("plugin %q is not a valid repository name", plugin.Name))
+		}
+
+		switch plugin.Channel {
+		case "", ChannelLatest, ChannelStable:
+		default:
+			errs = append(errs, fmt.Errorf("plugin %s has an unknown channel %q", plugin.Name, plugin.Channel))
+		}
+	}
+
+	if _, err := c.Policy.Compile(); err != nil {
+		errs = append(errs, fmt.Errorf("policy: %w", err))
+	}
+
+	feedNames := make(map[string]bool)
+	for i, feed := range c.Feeds {
+		if !isFeedName(feed.Name) {
+			errs = append(errs, fmt.Errorf("feeds[%d] must have a name with only letters, numbers, dashes and underscores", i))
+		}
+
+		if feedNames[strings.ToLower(feed.Name)] {
+			errs = append(errs, fmt.Errorf("feed %s is defined more than once", feed.Name))
+		}
+
+		if feed.ApiLevel < 0 {
+			errs = append(errs, fmt.Errorf("feed %s must have a positive api_level", feed.Name))
+		}
+
+		if feed.Outdated != "" && !IsOutdatedMode(feed.Outdated) {
+			errs = append(errs, fmt.Errorf("feed %s has an unknown outdated mode %q, expected show, hide or only", feed.Name, feed.Outdated))
+		}
+
+		feedNames[strings.ToLower(feed.Name)] = true
+	}
+
+	for i, override := range c.Overrides {
+		if override.InternalName == "" && override.Source == "" {
+			errs = append(errs, fmt.Errorf("overrides[%d] must have an internal_name or a source", i))
+		}
+
+		if override.Source != "" && !state.IsValidUrl(override.Source) {
+			errs = append(errs, fmt.Errorf("overrides[%d] has an invalid source URL %q", i, override.Source))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func readLegacyFile(path string, callback func(line string)) (bool, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	slog.Debug("Reading legacy source file", "path", path)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(strings.Trim(line, "\r"))
+		if line == "" {
+			continue
+		}
+
+		callback(line)
+	}
+
+	return true, nil
+}
+
+// IsOutdatedMode returns true if the value is a way to handle outdated plugins.
+func IsOutdatedMode(mode string) bool {
+	return mode == OutdatedShow || mode == OutdatedHide || mode == OutdatedOnly
+}
+
+func isFailurePolicy(policy string) bool {
+	return policy == FailurePolicyKeep || policy == FailurePolicyDegrade || policy == FailurePolicyRemove
+}
+
+func isRejectSeverity(severity string) bool {
+	return severity == validation.RejectNone || validation.IsSeverity(severity)
+}
+
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
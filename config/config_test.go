@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+	"github.com/senither/dalamud-plugin-listing/validation"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMergesLegacyFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(configPathEnv, "")
+
+	writeFile(t, "config.yml", `
+global:
+  expire_after: 5d
+sources:
+  - url: https://example.com/repo.json
+    name: Example
+    interval: 30m
+    tags:
+      add: [UI]
+plugins:
+  - name: Senither/Plugin
+    channel: stable
+`)
+
+	writeFile(t, "repositories.txt", "https://example.com/repo.json\r\nhttps://example.com/other.json\n")
+	writeFile(t, "plugins.txt", "P:Senither/Plugin\nP:gitlab:group/project\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	if len(cfg.Sources) != 2 || cfg.Source("https://example.com/repo.json").Name != "Example" {
+		t.Errorf("Expected the legacy source to be merged without overriding the config, got %+v", cfg.Sources)
+	}
+
+	if len(cfg.Plugins) != 2 {
+		t.Fatalf("Expected 2 plugins, got %+v", cfg.Plugins)
+	}
+
+	ip, ok := cfg.Plugins[1].InternalPlugin()
+	if !ok || !ip.Private || ip.Name != "group/project" {
+		t.Errorf("Expected the legacy private GitLab plugin to be parsed, got %+v", ip)
+	}
+
+	if cfg.Global.ExpireAfter.Duration() != 5*24*time.Hour {
+		t.Errorf("Expected expire_after to be 5 days, got %s", cfg.Global.ExpireAfter.Duration())
+	}
+
+	if cfg.Global.PluginInterval.Duration() != 12*time.Hour {
+		t.Errorf("Expected the default plugin interval to be kept, got %s", cfg.Global.PluginInterval.Duration())
+	}
+
+	if interval := cfg.Source("https://example.com/repo.json").NextInterval(cfg.Global); interval != 30*time.Minute {
+		t.Errorf("Expected the source interval to be used, got %s", interval)
+	}
+}
+
+func TestLoadRejectsInvalidSettings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(configPathEnv, "")
+
+	writeFile(t, "config.yml", `
+sources:
+  - url: https://example.com/repo.json
+    trust: maybe
+plugins:
+  - name: not-a-repository
+`)
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected invalid settings to be rejected")
+	}
+}
+
+func TestLoadRequiresSomeSources(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(configPathEnv, "")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error when there are no source files")
+	}
+}
+
+func TestSourceApplyTags(t *testing.T) {
+	source := Source{Tags: TagOverrides{Add: []string{"UI", "Utility"}, Remove: []string{"broken"}}}
+
+	tags := source.ApplyTags([]string{"Broken", "ui", "Combat"})
+
+	expected := []string{"ui", "Combat", "Utility"}
+	if len(tags) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, tags)
+	}
+
+	for i := range expected {
+		if tags[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, tags)
+		}
+	}
+}
+
+func TestTrustLevelChangesTheRejectSeverity(t *testing.T) {
+	global := Default().Global
+	global.Validation.Reject = validation.SeverityWarning
+
+	cases := []struct {
+		source   Source
+		expected string
+	}{
+		{Source{}, validation.SeverityWarning},
+		{Source{Trust: TrustTrusted}, validation.SeverityError},
+		{Source{Trust: TrustTrusted, Reject: validation.SeverityInfo}, validation.SeverityInfo},
+		{Source{Trust: TrustUntrusted}, validation.SeverityWarning},
+	}
+
+	for _, c := range cases {
+		if reject := c.source.ValidationOptions(global).Reject; reject != c.expected {
+			t.Errorf("Expected %s for %+v, got %s", c.expected, c.source, reject)
+		}
+	}
+
+	global.Validation.Reject = validation.SeverityError
+
+	if reject := (Source{Trust: TrustUntrusted}).ValidationOptions(global).Reject; reject != validation.SeverityWarning {
+		t.Errorf("Expected untrusted sources to reject warnings, got %s", reject)
+	}
+}
+
+func TestPluginOverridesTakePrecedenceOverSourceOverrides(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(configPathEnv, "")
+
+	writeFile(t, "config.yml", `
+sources:
+  - url: https://example.com/repo.json
+overrides:
+  - internal_name: Plugin
+    punchline: From the plugin override
+  - source: https://example.com/repo.json
+    punchline: From the source override
+    tags:
+      add: [UI]
+`)
+
+	iconUrl := "https://example.com/icon.png"
+	if err := SetOverride(Override{InternalName: "Other", IconUrl: &iconUrl}); err != nil {
+		t.Fatalf("Expected the override to be added, got %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	repo := state.Repository{InternalName: "plugin"}
+	repo.RepositoryOrigin.RepositoryUrl = "https://example.com/repo.json"
+
+	patched, matched := cfg.ApplyOverrides(repo)
+	if !matched || patched.Punchline == nil || *patched.Punchline != "From the plugin override" {
+		t.Errorf("Expected the plugin override to win, got %+v", patched.Punchline)
+	}
+
+	if len(patched.Tags) != 1 || patched.Tags[0] != "UI" {
+		t.Errorf("Expected the source override tags to be applied, got %v", patched.Tags)
+	}
+
+	if _, matched := cfg.ApplyOverrides(state.Repository{InternalName: "Unrelated"}); matched {
+		t.Error("Expected no override to match an unrelated plugin")
+	}
+
+	if !cfg.HasOverride(state.Repository{InternalName: "other"}) {
+		t.Error("Expected the override added through the config file to match")
+	}
+
+	if err := RemoveOverride("Other", ""); err != nil {
+		t.Errorf("Expected the override to be removed, got %v", err)
+	}
+
+	if err := RemoveOverride("Other", ""); !errors.Is(err, ErrOverrideNotFound) {
+		t.Errorf("Expected removing a missing override to fail, got %v", err)
+	}
+}
+
+func TestSourceNextIntervalStaysInRange(t *testing.T) {
+	global := Default().Global
+
+	for range 100 {
+		interval := Source{}.NextInterval(global)
+		if interval < 55*time.Minute || interval >= 70*time.Minute {
+			t.Fatalf("Expected interval within 55-70 minutes, got %s", interval)
+		}
+	}
+}
+
+func TestEditSourcesKeepsComments(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(configPathEnv, "")
+
+	writeFile(t, "config.yml", `# Managed sources
+sources:
+  # The example source
+  - url: https://example.com/repo.json
+    name: Example
+`)
+
+	writeFile(t, "repositories.txt", "https://example.com/legacy.json\n")
+
+	if err := AddSource("https://example.com/repo.json", ""); !errors.Is(err, ErrSourceExists) {
+		t.Errorf("Expected adding an existing source to fail, got %v", err)
+	}
+
+	if err := AddSource("https://example.com/new.json", "New"); err != nil {
+		t.Fatalf("Expected the source to be added, got %v", err)
+	}
+
+	if err := SetSourceEnabled("https://example.com/legacy.json", false); err != nil {
+		t.Fatalf("Expected the legacy source to be disabled, got %v", err)
+	}
+
+	if err := RemoveSource("https://example.com/repo.json"); err != nil {
+		t.Fatalf("Expected the source to be removed, got %v", err)
+	}
+
+	content, _ := os.ReadFile("config.yml")
+	if !strings.Contains(string(content), "# Managed sources") {
+		t.Errorf("Expected the comments to be kept, got:\n%s", content)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected config to load, got %v", err)
+	}
+
+	if len(cfg.Sources) != 2 || cfg.Source("https://example.com/new.json").Name != "New" {
+		t.Errorf("Expected the new and legacy sources to remain, got %+v", cfg.Sources)
+	}
+
+	if cfg.Source("https://example.com/legacy.json").IsEnabled() {
+		t.Errorf("Expected the legacy source to be disabled")
+	}
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	backoff := Backoff{Base: Duration(time.Minute), Max: Duration(5 * time.Minute)}
+
+	expected := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for failures, delay := range expected {
+		if actual := backoff.After(failures); actual != delay {
+			t.Errorf("Expected %s after %d failures, got %s", delay, failures, actual)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that is written as "90m" or "12h" in the config
+// file, a "d" suffix is also accepted for whole days like "3d".
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// After returns the delay after the given number of failures in a row.
+func (b Backoff) After(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	delay := b.Base.Duration()
+	for i := 1; i < failures && delay < b.Max.Duration(); i++ {
+		delay *= 2
+	}
+
+	return min(delay, b.Max.Duration())
+}
@@ -0,0 +1,333 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrSourceExists   = errors.New("config: source already exists")
+	ErrSourceNotFound = errors.New("config: source not found")
+
+	ErrOverrideNotFound = errors.New("config: override not found")
+)
+
+// editMu serializes edits made to the config files, the edits are made on the
+// YAML node tree so comments and formatting in the config file are kept.
+var editMu sync.Mutex
+
+// AddSource appends a new source to the config file.
+func AddSource(url string, name string) error {
+	return editConfigFile(func(root *yaml.Node) error {
+		sources := sequenceValue(root, "sources")
+
+		if findSource(sources, url) != nil || legacySourceExists(url) {
+			return ErrSourceExists
+		}
+
+		source := &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(source, "url", scalarNode(url))
+
+		if name != "" {
+			setMappingValue(source, "name", scalarNode(name))
+		}
+
+		sources.Content = append(sources.Content, source)
+		return nil
+	})
+}
+
+// SetSourceEnabled enables or disables a source in the config file, sources
+// that only exist in repositories.txt get an entry in the config file since
+// the settings from the config file take precedence.
+func SetSourceEnabled(url string, enabled bool) error {
+	return editConfigFile(func(root *yaml.Node) error {
+		sources := sequenceValue(root, "sources")
+
+		source := findSource(sources, url)
+		if source == nil {
+			if !legacySourceExists(url) {
+				return ErrSourceNotFound
+			}
+
+			source = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(source, "url", scalarNode(url))
+			sources.Content = append(sources.Content, source)
+		}
+
+		setMappingValue(source, "enabled", &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Tag:   "!!bool",
+			Value: strconv.FormatBool(enabled),
+		})
+
+		return nil
+	})
+}
+
+// RemoveSource removes a source from both the config file and the legacy
+// repositories.txt file.
+func RemoveSource(url string) error {
+	removedLegacy, err := removeLegacySource(url)
+	if err != nil {
+		return err
+	}
+
+	err = editConfigFile(func(root *yaml.Node) error {
+		sources := sequenceValue(root, "sources")
+
+		source := findSource(sources, url)
+		if source == nil {
+			return ErrSourceNotFound
+		}
+
+		sources.Content = slices.DeleteFunc(sources.Content, func(node *yaml.Node) bool {
+			return node == source
+		})
+
+		return nil
+	})
+
+	if errors.Is(err, ErrSourceNotFound) && removedLegacy {
+		return nil
+	}
+
+	return err
+}
+
+// SetOverride adds the override to the config file, or replaces the override
+// with the same InternalName and source.
+func SetOverride(override Override) error {
+	return editConfigFile(func(root *yaml.Node) error {
+		overrides := sequenceValue(root, "overrides")
+
+		node := &yaml.Node{}
+		if err := node.Encode(override); err != nil {
+			return err
+		}
+
+		if existing := findOverride(overrides, override.InternalName, override.Source); existing != nil {
+			*existing = *node
+			return nil
+		}
+
+		overrides.Content = append(overrides.Content, node)
+		return nil
+	})
+}
+
+// RemoveOverride removes the override with the InternalName and source from
+// the config file.
+func RemoveOverride(internalName string, source string) error {
+	return editConfigFile(func(root *yaml.Node) error {
+		overrides := sequenceValue(root, "overrides")
+
+		override := findOverride(overrides, internalName, source)
+		if override == nil {
+			return ErrOverrideNotFound
+		}
+
+		overrides.Content = slices.DeleteFunc(overrides.Content, func(node *yaml.Node) bool {
+			return node == override
+		})
+
+		return nil
+	})
+}
+
+// editConfigFile applies the edit to the config file and writes it back, the
+// edited config is validated first so a broken config is never written.
+func editConfigFile(edit func(root *yaml.Node) error) error {
+	editMu.Lock()
+	defer editMu.Unlock()
+
+	content, err := os.ReadFile(Path())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", Path(), err)
+	}
+
+	if document.Kind == 0 {
+		document = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode}},
+		}
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s must contain a mapping at the top level", Path())
+	}
+
+	if err := edit(root); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+
+	encoder.Close()
+
+	cfg := Default()
+	if err := yaml.Unmarshal(buffer.Bytes(), cfg); err != nil {
+		return err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(Path(), buffer.Bytes())
+}
+
+func readLegacySources() ([]string, error) {
+	var urls []string
+
+	_, err := readLegacyFile("repositories.txt", func(line string) {
+		urls = append(urls, line)
+	})
+
+	return urls, err
+}
+
+func legacySourceExists(url string) bool {
+	urls, _ := readLegacySources()
+	return slices.Contains(urls, url)
+}
+
+func removeLegacySource(url string) (bool, error) {
+	editMu.Lock()
+	defer editMu.Unlock()
+
+	content, err := os.ReadFile("repositories.txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	remaining := slices.DeleteFunc(slices.Clone(lines), func(line string) bool {
+		return strings.TrimSpace(strings.Trim(line, "\r")) == url
+	})
+
+	if len(remaining) == len(lines) {
+		return false, nil
+	}
+
+	return true, writeFileAtomic("repositories.txt", []byte(strings.Join(remaining, "\n")))
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode())
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func findSource(sources *yaml.Node, url string) *yaml.Node {
+	for _, source := range sources.Content {
+		if value := mappingValue(source, "url"); value != nil && value.Value == url {
+			return source
+		}
+	}
+
+	return nil
+}
+
+func findOverride(overrides *yaml.Node, internalName string, source string) *yaml.Node {
+	for _, override := range overrides.Content {
+		var existing Override
+		if err := override.Decode(&existing); err != nil {
+			continue
+		}
+
+		if strings.EqualFold(existing.InternalName, internalName) && existing.Source == source {
+			return override
+		}
+	}
+
+	return nil
+}
+
+// sequenceValue returns the sequence stored under the key, creating it when
+// it doesn't exist or is empty.
+func sequenceValue(mapping *yaml.Node, key string) *yaml.Node {
+	value := mappingValue(mapping, key)
+	if value != nil && value.Kind == yaml.SequenceNode {
+		return value
+	}
+
+	sequence := &yaml.Node{Kind: yaml.SequenceNode}
+	setMappingValue(mapping, key, sequence)
+
+	return sequence
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+var feedNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Feed is a curated plugin repository served at /feeds/<name>.json, it only
+// contains the plugins matching every filter that is set. The included
+// plugins are always added and the excluded plugins are always left out, a
+// feed with only an include list contains just those plugins.
+type Feed struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+	Authors     []string `yaml:"authors"`
+	Sources     []string `yaml:"sources"`
+	ApiLevel    int      `yaml:"api_level"`
+	// Outdated overrides global.api_level.outdated for the feed, the plugins
+	// are compared to the feed API level if it's set, or the latest level.
+	Outdated string   `yaml:"outdated"`
+	Include  []string `yaml:"include"`
+	Exclude  []string `yaml:"exclude"`
+}
+
+// Feed returns the feed with the given name, feed names are case-insensitive.
+func (c *Config) Feed(name string) (Feed, bool) {
+	for _, feed := range c.Feeds {
+		if strings.EqualFold(feed.Name, name) {
+			return feed, true
+		}
+	}
+
+	return Feed{}, false
+}
+
+func isFeedName(name string) bool {
+	return feedNamePattern.MatchString(name)
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// Override patches the plugins matching the InternalName, the source URL, or
+// both, before they're served. Fields that aren't set are left as they are,
+// so only the broken parts of an upstream entry need to be overridden.
+type Override struct {
+	InternalName string       `yaml:"internal_name,omitempty"`
+	Source       string       `yaml:"source,omitempty"`
+	Name         *string      `yaml:"name,omitempty"`
+	Author       *string      `yaml:"author,omitempty"`
+	Punchline    *string      `yaml:"punchline,omitempty"`
+	Description  *string      `yaml:"description,omitempty"`
+	IconUrl      *string      `yaml:"icon_url,omitempty"`
+	RepoUrl      *string      `yaml:"repo_url,omitempty"`
+	Tags         TagOverrides `yaml:"tags,omitempty"`
+}
+
+// Matches reports if the override applies to the plugin, both the InternalName
+// and the source must match when both are set.
+func (o Override) Matches(repo state.Repository) bool {
+	if o.InternalName == "" && o.Source == "" {
+		return false
+	}
+
+	if o.InternalName != "" && !strings.EqualFold(o.InternalName, repo.InternalName) {
+		return false
+	}
+
+	return o.Source == "" || o.Source == repo.RepositoryOrigin.RepositoryUrl
+}
+
+// Apply returns the plugin with the fields of the override patched in.
+func (o Override) Apply(repo state.Repository) state.Repository {
+	if o.Name != nil {
+		repo.Name = *o.Name
+	}
+
+	if o.Author != nil {
+		repo.Author = *o.Author
+	}
+
+	if o.Punchline != nil {
+		repo.Punchline = o.Punchline
+	}
+
+	if o.Description != nil {
+		repo.Description = *o.Description
+	}
+
+	if o.IconUrl != nil {
+		repo.IconUrl = o.IconUrl
+	}
+
+	if o.RepoUrl != nil {
+		repo.RepoUrl = o.RepoUrl
+	}
+
+	repo.Tags = o.Tags.Apply(repo.Tags)
+
+	return repo
+}
+
+// ApplyOverrides patches the plugin with every matching override, overrides
+// for a whole source are applied first so the overrides for a single plugin
+// take precedence. It reports if any override matched.
+func (c *Config) ApplyOverrides(repo state.Repository) (state.Repository, bool) {
+	matched := false
+
+	for _, override := range c.sortedOverrides() {
+		if override.Matches(repo) {
+			repo = override.Apply(repo)
+			matched = true
+		}
+	}
+
+	return repo, matched
+}
+
+// HasOverride reports if any override matches the plugin.
+func (c *Config) HasOverride(repo state.Repository) bool {
+	return slices.ContainsFunc(c.Overrides, func(override Override) bool {
+		return override.Matches(repo)
+	})
+}
+
+func (c *Config) sortedOverrides() []Override {
+	overrides := slices.Clone(c.Overrides)
+
+	slices.SortStableFunc(overrides, func(a, b Override) int {
+		return overrideSpecificity(a) - overrideSpecificity(b)
+	})
+
+	return overrides
+}
+
+func overrideSpecificity(override Override) int {
+	specificity := 0
+	if override.InternalName != "" {
+		specificity += 2
+	}
+
+	if override.Source != "" {
+		specificity++
+	}
+
+	return specificity
+}
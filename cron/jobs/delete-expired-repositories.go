@@ -1,25 +1,72 @@
 package jobs
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
+	"github.com/senither/dalamud-plugin-listing/config"
 	"github.com/senither/dalamud-plugin-listing/state"
 )
 
 func StartDeleteExpiredRepositoriesJob(interval time.Duration) {
-	tick := time.NewTicker(interval)
+	err := runner.Schedule(deleteExpiredJobName, interval, false, func(ctx context.Context) error {
+		runDelete()
+		return nil
+	})
 
-	go func() {
-		for range tick.C {
-			runDelete()
+	if err != nil {
+		slog.Error("Failed to schedule the expired repositories job", "err", err)
+	}
+}
+
+// GetExpiry returns when the oldest plugin from the origin URL is deleted by
+// the expiry job unless it's refreshed before then, and whether that happens
+// within half of the expiry window. The zero time is returned for origins
+// without plugins and for sources with an open circuit, since those plugins
+// are never expired.
+func GetExpiry(url string) (time.Time, bool) {
+	if state.GetSourceStatus(url).CircuitOpen {
+		return time.Time{}, false
+	}
+
+	var oldest int64
+	for _, repo := range state.GetRepositoriesByOriginUrl(url) {
+		if oldest == 0 || repo.RepositoryOrigin.LastUpdatedAt < oldest {
+			oldest = repo.RepositoryOrigin.LastUpdatedAt
 		}
-	}()
+	}
+
+	if oldest == 0 {
+		return time.Time{}, false
+	}
+
+	expireAfter := config.Get().Global.ExpireAfter.Duration()
+	expiresAt := time.Unix(oldest, 0).Add(expireAfter)
+
+	return expiresAt, time.Until(expiresAt) < expireAfter/2
 }
 
 func runDelete() {
-	for _, repo := range state.GetRepositories() {
-		if repo.RepositoryOrigin.LastUpdatedAt < time.Now().Add(time.Hour*24*3*-1).Unix() {
+	expireAfter := config.Get().Global.ExpireAfter.Duration()
+
+	repos := state.GetRepositories()
+	for _, entry := range state.GetShadowedRepositories() {
+		repos = append(repos, entry.Repository)
+	}
+
+	for _, entry := range state.GetBlockedRepositories() {
+		repos = append(repos, entry.Repository)
+	}
+
+	for _, repo := range repos {
+		// Plugins from a source with an open circuit are only stale because
+		// the source is broken, the failure policy decides what happens to them.
+		if state.GetSourceStatus(repo.RepositoryOrigin.RepositoryUrl).CircuitOpen {
+			continue
+		}
+
+		if repo.RepositoryOrigin.LastUpdatedAt < time.Now().Add(-expireAfter).Unix() {
 			var repoUrl string
 
 			if repo.RepoUrl == nil {
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestExpiredBlockedEntriesAreDeleted(t *testing.T) {
+	t.Setenv("APP_CACHE_DIR", t.TempDir())
+
+	state.SetRepositoryPolicy(func(repo state.Repository) (bool, string) {
+		return false, "denied"
+	})
+	defer state.SetRepositoryPolicy(func(repo state.Repository) (bool, string) { return true, "" })
+
+	repoUrl := "https://example.com/expired-blocked"
+	repo := state.Repository{
+		InternalName: "ExpiredBlocked",
+		Name:         "Expired Blocked",
+		RepoUrl:      &repoUrl,
+		RepositoryOrigin: state.RepositoryOrigin{
+			RepositoryUrl: "https://example.com/blocked/repo.json",
+			LastUpdatedAt: time.Now().Add(-30 * 24 * time.Hour).Unix(),
+		},
+	}
+
+	state.UpsertRepository(repo)
+	defer state.DeleteRepository(repo)
+
+	if !isBlocked(repo.InternalName) {
+		t.Fatal("Expected the entry to be blocked by the policy")
+	}
+
+	runDelete()
+
+	if isBlocked(repo.InternalName) {
+		t.Error("Expected the expired blocked entry to be deleted")
+	}
+}
+
+func isBlocked(internalName string) bool {
+	for _, entry := range state.GetBlockedRepositories() {
+		if entry.Repository.InternalName == internalName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// RunOnce updates every source and internal plugin once without scheduling
+// them, then deletes the expired plugins. At most concurrency updates run at
+// the same time, and the failed updates are returned keyed by the source URL
+// or plugin name.
+func RunOnce(ctx context.Context, concurrency int) map[string]error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]error)
+		slots    = make(chan struct{}, max(concurrency, 1))
+	)
+
+	run := func(key string, update func() error) {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := update(); err != nil {
+				mu.Lock()
+				failures[key] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, url := range state.GetUrls() {
+		run(url, func() error {
+			return runRepositoryUpdate(ctx, url)
+		})
+	}
+
+	for _, ip := range state.GetInternalPlugins() {
+		run(ip.Name, func() error {
+			return runUpdatePluginRelease(ctx, &ip)
+		})
+	}
+
+	wg.Wait()
+
+	slog.Info("Deleting expired repositories")
+	runDelete()
+
+	return failures
+}
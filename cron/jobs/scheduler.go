@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/senither/dalamud-plugin-listing/cron/scheduler"
+)
+
+// Job names are prefixed with the kind of job, so the statuses for each kind
+// can be looked up by the source URL or plugin name.
+const (
+	repositoryJobPrefix    = "repository:"
+	pluginReleaseJobPrefix = "plugin:"
+	deleteExpiredJobName   = "delete-expired-repositories"
+	dalamudApiLevelJobName = "dalamud-api-level"
+	releaseEventTaskPrefix = "release-event:"
+)
+
+var runner = scheduler.New()
+
+// Shutdown stops scheduling jobs and waits for the jobs in progress to finish,
+// they're cancelled if they haven't finished when the context expires.
+func Shutdown(ctx context.Context) error {
+	return runner.Shutdown(ctx)
+}
+
+// GetJobStatuses returns the status of every scheduled job, keyed by job name.
+func GetJobStatuses() map[string]scheduler.Status {
+	return runner.Statuses()
+}
+
+func jobStatusesWithPrefix(prefix string) map[string]scheduler.Status {
+	statuses := make(map[string]scheduler.Status)
+
+	for name, status := range runner.Statuses() {
+		if key, ok := strings.CutPrefix(name, prefix); ok {
+			statuses[key] = status
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// dalamudVersionInfo is the part of the Dalamud version info we care about,
+// the API level is the major version of Dalamud unless it's given directly.
+type dalamudVersionInfo struct {
+	AssemblyVersion string `json:"assemblyVersion"`
+	ApiLevel        int    `json:"apiLevel"`
+}
+
+// StartUpdateDalamudApiLevelJob reads the latest Dalamud API level from the
+// upstream version info URL on the given interval.
+func StartUpdateDalamudApiLevelJob(url string, interval time.Duration) {
+	err := runner.Schedule(dalamudApiLevelJobName, interval, true, func(ctx context.Context) error {
+		return runDalamudApiLevelUpdate(ctx, url)
+	})
+
+	if err != nil {
+		slog.Error("Failed to schedule the Dalamud API level job",
+			"err", err,
+			"url", url,
+		)
+	}
+}
+
+// StopUpdateDalamudApiLevelJob stops the job and forgets the upstream level,
+// so the latest level falls back to the levels of the plugins.
+func StopUpdateDalamudApiLevelJob() bool {
+	state.SetUpstreamDalamudApiLevel(0)
+
+	return runner.Stop(dalamudApiLevelJobName)
+}
+
+func runDalamudApiLevelUpdate(ctx context.Context, url string) error {
+	client := http.Client{Timeout: time.Minute}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Error("Failed to fetch the Dalamud version info",
+			"err", err,
+			"url", url,
+		)
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	level, err := decodeDalamudApiLevel(resp.Body)
+	if err != nil {
+		slog.Error("Failed to read the Dalamud API level",
+			"err", err,
+			"url", url,
+		)
+		return err
+	}
+
+	if previous := state.GetLatestDalamudApiLevel(); previous != level {
+		slog.Info("Updated the latest Dalamud API level",
+			"previous", previous,
+			"level", level,
+		)
+	}
+
+	state.SetUpstreamDalamudApiLevel(level)
+
+	return nil
+}
+
+func decodeDalamudApiLevel(body io.Reader) (state.ApiLevel, error) {
+	var info dalamudVersionInfo
+	if err := json.NewDecoder(body).Decode(&info); err != nil {
+		return 0, err
+	}
+
+	if info.ApiLevel > 0 {
+		return state.ApiLevel(info.ApiLevel), nil
+	}
+
+	parts := state.Version(info.AssemblyVersion).Parts()
+	if len(parts) == 0 || parts[0] <= 0 {
+		return 0, fmt.Errorf("no API level or assembly version in the version info")
+	}
+
+	return state.ApiLevel(parts[0]), nil
+}
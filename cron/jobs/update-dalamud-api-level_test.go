@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestDecodeDalamudApiLevelFromVersionInfo(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		expected state.ApiLevel
+	}{
+		{"api level", `{"assemblyVersion": "12.0.1.3", "apiLevel": 13}`, 13},
+		{"assembly version", `{"assemblyVersion": "12.0.1.3"}`, 12},
+	}
+
+	for _, c := range cases {
+		level, err := decodeDalamudApiLevel(strings.NewReader(c.body))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		if level != c.expected {
+			t.Errorf("%s: expected API level %d, got %d", c.name, c.expected, level)
+		}
+	}
+
+	if _, err := decodeDalamudApiLevel(strings.NewReader(`{"track": "release"}`)); err == nil {
+		t.Error("Expected an error for version info without a version")
+	}
+}
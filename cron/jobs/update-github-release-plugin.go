@@ -1,28 +1,18 @@
 package jobs
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"io"
 	"log/slog"
-	"net/http"
-	"os"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/senither/dalamud-plugin-listing/cron/scheduler"
+	"github.com/senither/dalamud-plugin-listing/forge"
 	"github.com/senither/dalamud-plugin-listing/state"
 )
 
-type UpdatePluginReleaseJob struct {
-	Interval     time.Duration
-	RunOnStartup bool
-	Ticker       *time.Ticker
-}
-
-var jobs = make(map[string]*UpdatePluginReleaseJob)
-
 func StartUpdatePluginReleaseJob(repoName string, interval time.Duration, runOnStartup bool) {
 	slog.Info("Starting update plugin release job",
 		"repoName", repoName,
@@ -30,111 +20,205 @@ func StartUpdatePluginReleaseJob(repoName string, interval time.Duration, runOnS
 		"runOnStartup", runOnStartup,
 	)
 
-	ip := state.GetInternalPluginByName(repoName)
-	if ip == nil {
+	if state.GetInternalPluginByName(repoName) == nil {
 		slog.Error("Failed to find internal plugin for GitHub release update job",
 			"repoName", repoName,
 		)
 		return
 	}
 
-	if runOnStartup {
-		runUpdatePluginRelease(ip)
-	}
+	err := runner.Schedule(pluginReleaseJobPrefix+repoName, interval, runOnStartup, func(ctx context.Context) error {
+		// The plugin is looked up on every run so changes made to it while
+		// the server is running are picked up by the job.
+		ip := state.GetInternalPluginByName(repoName)
+		if ip == nil {
+			return ErrUnknownPlugin
+		}
 
-	tick := time.NewTicker(interval)
+		return runUpdatePluginRelease(ctx, ip)
+	})
 
-	jobs[repoName] = &UpdatePluginReleaseJob{
-		Interval:     interval,
-		RunOnStartup: runOnStartup,
-		Ticker:       tick,
+	if err != nil {
+		slog.Error("Failed to schedule plugin release job",
+			"err", err,
+			"repoName", repoName,
+		)
 	}
+}
 
-	go func() {
-		for range tick.C {
-			runUpdatePluginRelease(ip)
-		}
-	}()
+// StopUpdatePluginReleaseJob stops the release job for the given plugin,
+// returning false if no job was running for it.
+func StopUpdatePluginReleaseJob(repoName string) bool {
+	return runner.Stop(pluginReleaseJobPrefix + repoName)
 }
 
-func RunGitHubReleaseUpdateJob(repoName string) {
+var (
+	ErrUnknownPlugin = errors.New("unknown internal plugin")
+
+	errMissingReleaseAssets = errors.New("manifest or release asset is missing from the release")
+
+	// missingAssetRetryDelays are the delays between fetching the releases
+	// again when a webhook arrives before the release assets are uploaded.
+	missingAssetRetryDelays = []time.Duration{15 * time.Second, 45 * time.Second, 2 * time.Minute}
+)
+
+// RunGitHubReleaseUpdateJob fetches the releases for the internal plugin
+// immediately, outside of its regular schedule.
+func RunGitHubReleaseUpdateJob(repoName string) error {
 	ip := state.GetInternalPluginByName(repoName)
 	if ip == nil {
 		slog.Error("Failed to find internal plugin for GitHub release update job",
 			"repoName", repoName,
 		)
-		return
+		return ErrUnknownPlugin
+	}
+
+	err := runner.Trigger(pluginReleaseJobPrefix + ip.Name)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		return runUpdatePluginRelease(context.Background(), ip)
 	}
 
-	runUpdatePluginRelease(ip)
+	return err
 }
 
-func GetPluginReleasesJobs() map[string]*UpdatePluginReleaseJob {
-	return jobs
+// GetPluginReleasesJobs returns the status of every plugin release job, keyed
+// by the plugin name.
+func GetPluginReleasesJobs() map[string]scheduler.Status {
+	return jobStatusesWithPrefix(pluginReleaseJobPrefix)
 }
 
-func runUpdatePluginRelease(ip *state.InternalPlugin) {
-	var githubToken = ""
-	if ip.Private {
-		githubToken = os.Getenv("GITHUB_TOKEN")
-		if githubToken == "" {
-			slog.Error("Cannot update private plugin release, missing GITHUB_TOKEN",
-				"repoName", ip.Name,
-			)
-			return
+func runUpdatePluginRelease(ctx context.Context, ip *state.InternalPlugin) error {
+	err := updatePluginRelease(ctx, ip, false)
+	state.RecordPluginFetch(ip.Name, err)
+
+	return err
+}
+
+// ApplyGitHubReleaseEvent updates the plugin using the release sent in a
+// webhook, only falling back to fetching every release if there's no release
+// metadata for the plugin yet. Assets are often uploaded after a release is
+// published, so the releases are fetched again a few times if the manifest or
+// plugin archive is missing.
+func ApplyGitHubReleaseEvent(ctx context.Context, repoName string, action string, release state.GitHubPluginRelease) error {
+	ip := state.GetInternalPluginByName(repoName)
+	if ip == nil {
+		return ErrUnknownPlugin
+	}
+
+	err := applyGitHubReleaseEvent(ctx, ip, action, release)
+
+	for _, delay := range missingAssetRetryDelays {
+		if !errors.Is(err, errMissingReleaseAssets) {
+			break
 		}
+
+		slog.Info("Release assets are not available yet, fetching the releases again later",
+			"repoName", ip.Name,
+			"tag", release.TagName,
+			"delay", delay,
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		err = updatePluginRelease(ctx, ip, true)
 	}
 
-	slog.Info("Sending request to update plugin release for",
+	state.RecordPluginFetch(ip.Name, err)
+
+	return err
+}
+
+// QueueGitHubReleaseEvent applies the release event in the background.
+func QueueGitHubReleaseEvent(repoName string, action string, release state.GitHubPluginRelease) error {
+	return runner.Go(releaseEventTaskPrefix+repoName, func(ctx context.Context) error {
+		return ApplyGitHubReleaseEvent(ctx, repoName, action, release)
+	})
+}
+
+func applyGitHubReleaseEvent(ctx context.Context, ip *state.InternalPlugin, action string, release state.GitHubPluginRelease) error {
+	releases, ok := state.ApplyReleaseEvent(ip.Name, action, release)
+	if !ok {
+		return updatePluginRelease(ctx, ip, true)
+	}
+
+	slog.Info("Applying release event to plugin",
 		"repoName", ip.Name,
-		"private", ip.Private,
+		"action", action,
+		"tag", release.TagName,
 	)
 
-	client := http.Client{}
-	releaseReq, releasesErr := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", ip.Name), nil)
-	if releasesErr != nil {
-		slog.Error("Failed to create plugin release request",
-			"err", releasesErr,
-			"repoName", ip.Name,
-		)
-		return
+	provider, err := pluginProvider(ip)
+	if err != nil {
+		return err
 	}
 
-	releaseReq.Header.Set("Accept", "application/json")
-	releaseReq.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
 
-	if ip.Private {
-		releaseReq.Header.Set("Authorization", "Bearer "+githubToken)
+	return buildPluginRepository(ctx, provider, ip, releases)
+}
+
+func pluginProvider(ip *state.InternalPlugin) (forge.Provider, error) {
+	provider, err := forge.For(*ip)
+	if err != nil {
+		slog.Error("Cannot update plugin release, unsupported provider",
+			"err", err,
+			"repoName", ip.Name,
+		)
+		return nil, err
 	}
 
-	releaseResp, releasesErr := client.Do(releaseReq)
-	if releasesErr != nil {
-		slog.Error("Failed to communicate with GitHub API",
-			"err", releasesErr,
+	if ip.Private && !provider.HasToken(*ip) {
+		slog.Error("Cannot update private plugin release, missing provider token",
 			"repoName", ip.Name,
+			"provider", ip.Provider,
 		)
-		return
+		return nil, errors.New("missing provider token for private plugin")
+	}
+
+	return provider, nil
+}
+
+// updatePluginRelease fetches the releases for the plugin and rebuilds the
+// plugin entry from the latest release, unless the releases are unchanged and
+// the rebuild isn't forced.
+func updatePluginRelease(ctx context.Context, ip *state.InternalPlugin, force bool) error {
+	provider, err := pluginProvider(ip)
+	if err != nil {
+		return err
 	}
 
-	defer releaseResp.Body.Close()
+	slog.Info("Sending request to update plugin release for",
+		"repoName", ip.Name,
+		"provider", ip.Provider,
+		"private", ip.Private,
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
 
-	releases, releasesErr := decodeJsonPluginReleaseRequestBody(releaseResp.Body)
+	releases, releasesErr := provider.ListReleases(ctx, *ip)
 	if releasesErr != nil {
-		slog.Error("Failed to decode JSON response",
+		slog.Error("Failed to fetch releases from the provider",
 			"err", releasesErr,
 			"repoName", ip.Name,
+			"provider", ip.Provider,
 		)
-		return
+		return releasesErr
 	}
 
 	if len(releases) == 0 {
 		slog.Error("Failed to find any releases for repository",
 			"repoName", ip.Name,
 		)
-		return
+		return errors.New("no releases found")
 	}
 
-	if !state.UpsertReleaseMetadata(ip.Name, releases) {
+	if !state.UpsertReleaseMetadata(ip.Name, releases) && !force {
 		slog.Info("No changes detected in releases, skipping processing",
 			"repoName", ip.Name,
 		)
@@ -148,17 +232,29 @@ func runUpdatePluginRelease(ip *state.InternalPlugin) {
 			state.TouchRepository(*repository)
 		}
 
-		return
+		return nil
+	}
+
+	return buildPluginRepository(ctx, provider, ip, releases)
+}
+
+func buildPluginRepository(ctx context.Context, provider forge.Provider, ip *state.InternalPlugin, releases []state.GitHubPluginRelease) error {
+	latestRelease, ok := ip.LatestRelease(releases)
+	if !ok {
+		slog.Error("Failed to find a release matching the plugin channel",
+			"repoName", ip.Name,
+		)
+		return errors.New("no release matches the plugin channel")
 	}
 
-	var manifestAsset, releaseAsset = state.GetManifestAndLatestReleaseAssets(releases[0])
+	var manifestAsset, releaseAsset = ip.FindReleaseAssets(latestRelease)
 	if manifestAsset == nil || releaseAsset == nil {
 		slog.Error("Failed to find a manifest or release asset in the release",
 			"repoName", ip.Name,
 			"release", releaseAsset,
 			"manifest", manifestAsset,
 		)
-		return
+		return errMissingReleaseAssets
 	}
 
 	manifestUrl := manifestAsset.BrowserDownloadUrl
@@ -166,59 +262,49 @@ func runUpdatePluginRelease(ip *state.InternalPlugin) {
 		manifestUrl = manifestAsset.Url
 	}
 
-	assetReq, assetErr := http.NewRequest("GET", manifestUrl, nil)
-	if assetErr != nil {
-		slog.Error("Failed to create asset request",
-			"err", assetErr,
-			"repoName", ip.Name,
-			"manifestAsset", manifestAsset,
-			"downloadUrl", manifestAsset.BrowserDownloadUrl,
-		)
-		return
-	}
-
-	assetReq.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
-
-	if ip.Private {
-		assetReq.Header.Set("Authorization", "Bearer "+githubToken)
-		assetReq.Header.Set("Accept", "application/octet-stream")
-	}
-
-	manifestResp, assetErr := client.Do(assetReq)
+	manifestResp, assetErr := provider.DownloadAsset(ctx, *ip, manifestUrl)
 	if assetErr != nil {
-		slog.Error("Failed to communicate with asset URL",
+		slog.Error("Failed to download the manifest asset",
 			"err", assetErr,
 			"repoName", ip.Name,
 			"downloadUrl", manifestAsset.BrowserDownloadUrl,
 		)
-		return
+		return assetErr
 	}
 
 	defer manifestResp.Body.Close()
 
 	manifestBytes, assetErr := io.ReadAll(manifestResp.Body)
+	state.SetPluginResponse(ip.Name, manifestResp.StatusCode, int64(len(manifestBytes)))
+
 	if assetErr != nil {
 		slog.Error("Failed to read asset response body",
 			"err", assetErr,
 			"repoName", ip.Name,
 			"downloadUrl", manifestAsset.BrowserDownloadUrl,
 		)
-		return
+		return assetErr
 	}
 
-	var repository state.Repository
-	manifestErr := json.Unmarshal(manifestBytes, &repository)
+	repository, warnings, manifestErr := state.DecodeRepository(manifestBytes)
 	if manifestErr != nil {
 		slog.Error("Failed to decode JSON manifest",
 			"err", manifestErr,
 			"repoName", ip.Name,
 		)
-		return
+		return manifestErr
+	}
+
+	for _, warning := range warnings {
+		slog.Warn("Plugin manifest has an invalid field",
+			"repoName", ip.Name,
+			"warning", warning,
+		)
 	}
 
 	var truthy = true
 
-	var repoUrl = fmt.Sprintf("https://github.com/%s", ip.Name)
+	var repoUrl = ip.RepositoryUrl()
 	var repositoryOrigin = state.RepositoryOrigin{
 		LastUpdatedAt:    time.Now().Unix(),
 		RepositoryUrl:    repoUrl,
@@ -237,44 +323,25 @@ func runUpdatePluginRelease(ip *state.InternalPlugin) {
 
 	downloadUrl := releaseAsset.BrowserDownloadUrl
 	if ip.Private {
-		downloadUrl = state.GetDownloadUrlForPrivatePlugin(ip.Name, releases[0].TagName, releaseAsset)
+		downloadUrl = state.GetDownloadUrlForPrivatePlugin(ip.Name, latestRelease.TagName, releaseAsset)
 	}
 
-	releaseBody := releases[0].Body
+	releaseBody := latestRelease.Body
 	repository.Changelog = &releaseBody
 
-	t, err := time.Parse(time.RFC3339, releases[0].CreatedAt)
+	t, err := time.Parse(time.RFC3339, latestRelease.CreatedAt)
 	if err == nil {
-		repository.LastUpdate = t.Unix()
+		repository.LastUpdate = state.Timestamp{Time: t}
 	}
 
 	repository.RepoUrl = &repoUrl
 	repository.DownloadLinkInstall = &downloadUrl
 	repository.DownloadLinkUpdate = &downloadUrl
 	repository.RepositoryOrigin = repositoryOrigin
-	repository.DownloadCount = totalDownloadCount
+	downloadCount := state.Count(totalDownloadCount)
+	repository.DownloadCount = &downloadCount
 
 	state.UpsertRepository(repository)
-}
-
-func decodeJsonPluginReleaseRequestBody(body io.ReadCloser) ([]state.GitHubPluginRelease, error) {
-	reqBytes, err := io.ReadAll(body)
-	if err != nil {
-		return nil, err
-	}
-
-	reqBody := string(reqBytes)
-	exp := regexp.MustCompile(`,(\s*[\}\]])`)
-	reqBody = exp.ReplaceAllString(reqBody, "$1")
-
-	var release []state.GitHubPluginRelease
-
-	decoder := json.NewDecoder(bytes.NewBufferString(reqBody))
-	err = decoder.Decode(&release)
-
-	if err != nil {
-		return nil, err
-	}
 
-	return release, nil
+	return nil
 }
@@ -2,87 +2,251 @@ package jobs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/cron/scheduler"
+	"github.com/senither/dalamud-plugin-listing/metrics"
 	"github.com/senither/dalamud-plugin-listing/state"
+	"github.com/senither/dalamud-plugin-listing/validation"
 )
 
-type UpdateRepositoryJob struct {
-	Interval     time.Duration
-	RunOnStartup bool
-	Ticker       *time.Ticker
+func StartUpdateRepositoryJob(url string, interval time.Duration, runOnStartup bool) {
+	err := runner.Schedule(repositoryJobPrefix+url, interval, runOnStartup, func(ctx context.Context) error {
+		return runRepositoryUpdate(ctx, url)
+	})
+
+	if err != nil {
+		slog.Error("Failed to schedule repository update job",
+			"err", err,
+			"url", url,
+		)
+	}
 }
 
-var repositoryJobs = make(map[string]*UpdateRepositoryJob)
+// StopUpdateRepositoryJob stops the update job for the given URL, returning
+// false if no job was running for it.
+func StopUpdateRepositoryJob(url string) bool {
+	return runner.Stop(repositoryJobPrefix + url)
+}
 
-func StartUpdateRepositoryJob(url string, interval time.Duration, runOnStartup bool) {
-	if runOnStartup {
-		runRepositoryUpdate(url)
+// GetRepositoryJobs returns the status of every repository job, keyed by URL.
+func GetRepositoryJobs() map[string]scheduler.Status {
+	return jobStatusesWithPrefix(repositoryJobPrefix)
+}
+
+// RunRepositoryUpdateJob fetches the repository source immediately, outside
+// of its regular schedule.
+func RunRepositoryUpdateJob(url string) error {
+	// Manual refreshes skip the backoff, the circuit stays open until the
+	// fetch succeeds.
+	status := state.GetSourceStatus(url)
+	state.SetSourceBackoff(url, time.Time{}, status.CircuitOpen)
+
+	err := runner.Trigger(repositoryJobPrefix + url)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		return runRepositoryUpdate(context.Background(), url)
 	}
 
-	tick := time.NewTicker(interval)
+	return err
+}
+
+var errSourceInBackoff = errors.New("source is failing, skipped until its backoff expires")
+
+// runRepositoryUpdate fetches the source unless it's backing off after failed
+// fetches. Every failure in a row doubles the backoff, and once the failure
+// threshold is reached the circuit breaker opens and the failure policy for
+// the source is applied to its plugins.
+func runRepositoryUpdate(ctx context.Context, url string) error {
+	previous := state.GetSourceStatus(url)
+	if previous.InBackoff() {
+		slog.Info("Skipping repository update while the source is backing off",
+			"url", url,
+			"failures", previous.ConsecutiveFailures,
+			"retryAt", previous.RetryAt,
+		)
 
-	repositoryJobs[url] = &UpdateRepositoryJob{
-		Interval:     interval,
-		RunOnStartup: runOnStartup,
-		Ticker:       tick,
+		metrics.IncrementSourceFetchCounter(metrics.SourceFetchSkipped)
+		return errSourceInBackoff
 	}
 
-	go func() {
-		for range tick.C {
-			runRepositoryUpdate(url)
+	err := updateRepository(ctx, url)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	status := state.RecordSourceFetch(url, err)
+
+	cfg := config.Get()
+	settings := cfg.Source(url)
+
+	if err == nil {
+		metrics.IncrementSourceFetchCounter(metrics.SourceFetchSuccess)
+		metrics.SetSourceCircuitState(url, state.CircuitClosed, 0)
+
+		if previous.CircuitOpen {
+			slog.Info("Repository source recovered, closing the circuit",
+				"url", url,
+				"failures", previous.ConsecutiveFailures,
+			)
+
+			state.SetRepositoriesDegradedByOriginUrl(url, false)
 		}
-	}()
+
+		return nil
+	}
+
+	circuitOpen := status.ConsecutiveFailures >= cfg.Global.FailureThreshold
+	retryAt := time.Now().Add(cfg.Global.FailureBackoff.After(status.ConsecutiveFailures))
+
+	state.SetSourceBackoff(url, retryAt, circuitOpen)
+	scheduleRepositoryRetry(url, retryAt)
+
+	metrics.IncrementSourceFetchCounter(metrics.SourceFetchFailed)
+	metrics.SetSourceCircuitState(url, state.GetSourceStatus(url).Circuit(), status.ConsecutiveFailures)
+
+	if circuitOpen && !previous.CircuitOpen {
+		applyFailurePolicy(url, settings.FailurePolicy(cfg.Global), status.ConsecutiveFailures)
+	}
+
+	return err
+}
+
+// scheduleRepositoryRetry fetches the source again once its backoff expires,
+// the scheduled runs are usually further apart than the first backoff steps
+// and are skipped while the source is backing off.
+func scheduleRepositoryRetry(url string, retryAt time.Time) {
+	time.AfterFunc(time.Until(retryAt), func() {
+		// The outcome is recorded by the run itself, and the job is unknown if
+		// the source was removed in the meantime.
+		runner.Trigger(repositoryJobPrefix + url)
+	})
 }
 
-func GetRepositoryJobs() map[string]*UpdateRepositoryJob {
-	return repositoryJobs
+func applyFailurePolicy(url string, policy string, failures int) {
+	slog.Warn("Repository source keeps failing, opening the circuit",
+		"url", url,
+		"failures", failures,
+		"policy", policy,
+	)
+
+	switch policy {
+	case config.FailurePolicyDegrade:
+		state.SetRepositoriesDegradedByOriginUrl(url, true)
+	case config.FailurePolicyRemove:
+		removed := state.DeleteRepositoriesByOriginUrl(url)
+
+		// The validators are cleared so the plugins are added back once the
+		// source recovers, even if it answers with a 304 Not Modified.
+		state.ClearSourceValidators(url)
+
+		slog.Warn("Removed the plugins from the failing source",
+			"url", url,
+			"plugins", removed,
+		)
+	}
 }
 
-func runRepositoryUpdate(url string) {
+func updateRepository(ctx context.Context, url string) error {
 	slog.Info("Sending request to update repository for",
 		"url", url,
 	)
 
-	client := http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+	client := http.Client{Timeout: time.Minute}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		slog.Error("Failed to create repository update request",
 			"err", err,
 			"url", url,
 		)
-		return
+		return err
 	}
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
 
+	cfg := config.Get()
+	settings := cfg.Source(url)
+	for key, value := range settings.Headers {
+		req.Header.Set(key, value)
+	}
+
+	source := state.GetSourceStatus(url)
+	if source.ETag != "" {
+		req.Header.Set("If-None-Match", source.ETag)
+	}
+
+	if source.LastModified != "" {
+		req.Header.Set("If-Modified-Since", source.LastModified)
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
 		slog.Error("Failed to communicate with repository URL",
 			"err", err,
 			"url", url,
 		)
-		return
+		return err
 	}
 
 	defer resp.Body.Close()
 
-	repos, err := decodeJsonRequestBody(resp.Body)
+	if resp.StatusCode == http.StatusNotModified {
+		// The size and warnings from the last full response still apply, since
+		// the repository hasn't changed since then.
+		state.SetSourceResponse(url, resp.StatusCode, source.ResponseSize, source.Warnings)
+
+		touched := state.TouchRepositoriesByOriginUrl(url)
+
+		slog.Info("Repository has not been modified, touching existing plugins",
+			"url", url,
+			"plugins", touched,
+		)
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		state.SetSourceResponse(url, resp.StatusCode, 0, nil)
+
+		slog.Error("Received unexpected status code from repository URL",
+			"status", resp.StatusCode,
+			"url", url,
+		)
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	repos, size, warnings, err := DecodeJsonRequestBody(resp.Body)
+	state.SetSourceResponse(url, resp.StatusCode, size, warnings)
+
 	if err != nil {
 		slog.Error("Failed to decode JSON response",
 			"err", err,
 			"url", url,
 		)
-		return
+		return err
 	}
 
-	for _, repo := range repos {
+	// The validators are only stored once the body has been decoded, that way
+	// a broken response is fetched again in full on the next run.
+	state.SetSourceValidators(url, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+
+	accepted, results := validation.Validate(repos, settings.ValidationOptions(cfg.Global))
+	state.SetSourceValidation(url, results)
+
+	removeRejectedRepositories(url, accepted, results)
+
+	for _, repo := range accepted {
+		repo.Tags = settings.ApplyTags(repo.Tags)
 		repo.RepositoryOrigin = state.RepositoryOrigin{
 			RepositoryUrl: url,
 			LastUpdatedAt: time.Now().Unix(),
@@ -90,25 +254,92 @@ func runRepositoryUpdate(url string) {
 
 		state.UpsertRepository(repo)
 	}
+
+	return nil
 }
 
-func decodeJsonRequestBody(body io.ReadCloser) ([]state.Repository, error) {
+// removeRejectedRepositories removes the plugins from the source that were
+// rejected by the validation, so they're dropped from the listing right away
+// instead of when they expire. Plugins that share the InternalName with an
+// accepted entry are left alone, since the accepted entry replaces them.
+func removeRejectedRepositories(url string, accepted []state.Repository, results []state.ValidationResult) {
+	for _, result := range results {
+		if !result.Rejected {
+			continue
+		}
+
+		slog.Warn("Rejected plugin entry that failed validation",
+			"url", url,
+			"index", result.Index,
+			"internalName", result.InternalName,
+			"issues", validation.Summary(result),
+		)
+
+		// Entries without an InternalName can't match an existing plugin.
+		if result.InternalName == "" {
+			continue
+		}
+
+		if slices.ContainsFunc(accepted, func(repo state.Repository) bool {
+			return strings.EqualFold(repo.InternalName, result.InternalName)
+		}) {
+			continue
+		}
+
+		for _, existing := range state.GetRepositoriesByOriginUrl(url) {
+			if strings.EqualFold(existing.InternalName, result.InternalName) {
+				state.DeleteRepository(existing)
+			}
+		}
+	}
+}
+
+// DecodeJsonRequestBody decodes the plugins from a repository response,
+// returning the size of the response and a warning for every plugin entry
+// that had to be skipped or had a field that couldn't be parsed.
+func DecodeJsonRequestBody(body io.ReadCloser) ([]state.Repository, int64, []string, error) {
 	reqBytes, err := io.ReadAll(body)
+	size := int64(len(reqBytes))
+
 	if err != nil {
-		return nil, err
+		return nil, size, nil, err
 	}
 
 	reqBody := string(reqBytes)
 	exp := regexp.MustCompile(`,(\s*[\}\]])`)
 	reqBody = exp.ReplaceAllString(reqBody, "$1")
 
-	var repos []state.Repository
+	var entries []json.RawMessage
 
 	decoder := json.NewDecoder(bytes.NewBufferString(reqBody))
-	err = decoder.Decode(&repos)
+	err = decoder.Decode(&entries)
 
 	if err != nil {
-		return nil, err
+		return nil, size, nil, err
+	}
+
+	// Entries are decoded one by one so a single plugin with a malformed
+	// field doesn't prevent the rest of the repository from being updated.
+	var repos []state.Repository
+	var warnings []string
+	for i, entry := range entries {
+		repo, fieldWarnings, err := state.DecodeRepository(entry)
+		if err != nil {
+			slog.Warn("Skipping malformed plugin entry",
+				"err", err,
+				"index", i,
+			)
+
+			warnings = append(warnings, fmt.Sprintf("Skipped malformed plugin entry #%d: %v", i, err))
+			continue
+		}
+
+		for _, warning := range fieldWarnings {
+			warnings = append(warnings, fmt.Sprintf("Plugin entry #%d (%s): %s", i, repo.InternalName, warning))
+		}
+
+		repos = append(repos, repo)
 	}
-	return repos, nil
+
+	return repos, size, warnings, nil
 }
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestFailingSourceBacksOffAndOpensCircuit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Global.FailureThreshold = 2
+	cfg.Global.FailureBackoff = config.Backoff{Base: config.Duration(time.Minute), Max: config.Duration(time.Hour)}
+
+	previous := config.Get()
+	config.Set(cfg)
+	defer config.Set(previous)
+	defer state.DeleteSourceStatus(server.URL)
+
+	if err := runRepositoryUpdate(context.Background(), server.URL); err == nil {
+		t.Fatal("Expected the first fetch to fail")
+	}
+
+	status := state.GetSourceStatus(server.URL)
+	if status.ConsecutiveFailures != 1 || !status.InBackoff() || status.Circuit() != state.CircuitClosed {
+		t.Fatalf("Expected the source to back off with a closed circuit, got %+v", status)
+	}
+
+	if err := runRepositoryUpdate(context.Background(), server.URL); !errors.Is(err, errSourceInBackoff) {
+		t.Fatalf("Expected the fetch to be skipped during the backoff, got %v", err)
+	}
+
+	state.SetSourceBackoff(server.URL, time.Time{}, false)
+
+	runRepositoryUpdate(context.Background(), server.URL)
+
+	status = state.GetSourceStatus(server.URL)
+	if status.ConsecutiveFailures != 2 || status.Circuit() != state.CircuitOpen {
+		t.Errorf("Expected the circuit to open after 2 failures, got %+v", status)
+	}
+
+	if delay := time.Until(status.RetryAt); delay < time.Minute || delay > 2*time.Minute {
+		t.Errorf("Expected the backoff to double to 2 minutes, got %s", delay)
+	}
+}
+
+func TestFailingSourceIsRetriedWhenItsBackoffExpires(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Global.FailureBackoff = config.Backoff{Base: config.Duration(10 * time.Millisecond), Max: config.Duration(10 * time.Millisecond)}
+
+	previous := config.Get()
+	config.Set(cfg)
+	defer config.Set(previous)
+	defer state.DeleteSourceStatus(server.URL)
+
+	StartUpdateRepositoryJob(server.URL, time.Hour, true)
+	defer StopUpdateRepositoryJob(server.URL)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if requests.Load() < 2 {
+		t.Errorf("Expected the source to be retried before its next scheduled run, got %d requests", requests.Load())
+	}
+}
+
+func TestDecodeJsonRequestBodyReportsSkippedEntries(t *testing.T) {
+	body := `[{"InternalName": "First"}, {"InternalName": 42}, {"InternalName": "Third"},]`
+
+	repos, size, warnings, err := DecodeJsonRequestBody(io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("Expected the body to decode, got %v", err)
+	}
+
+	if len(repos) != 2 || size != int64(len(body)) {
+		t.Errorf("Expected 2 plugins from %d bytes, got %d plugins from %d bytes", len(body), len(repos), size)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "#1") {
+		t.Errorf("Expected a warning for the malformed entry, got %v", warnings)
+	}
+}
+
+func TestDecodeJsonRequestBodyKeepsEntriesWithInvalidOptionalFields(t *testing.T) {
+	body := `[{"InternalName": "Plugin", "DalamudApiLevel": 12, "LastUpdate": "2024-01-01", "DownloadCount": "N/A", "IsHide": "maybe"}]`
+
+	repos, _, warnings, err := DecodeJsonRequestBody(io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("Expected the body to decode, got %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].DalamudApiLevel != 12 || repos[0].DownloadCount != nil || !repos[0].LastUpdate.IsZero() {
+		t.Fatalf("Expected the entry to be kept with the invalid fields reset, got %+v", repos)
+	}
+
+	if len(warnings) != 3 || !strings.Contains(warnings[0], "DownloadCount") {
+		t.Errorf("Expected a warning for every invalid field, got %v", warnings)
+	}
+}
+
+func TestRejectedEntriesWithoutInternalNameKeepExistingEntries(t *testing.T) {
+	t.Setenv("APP_CACHE_DIR", t.TempDir())
+
+	url := "https://example.com/nameless/repo.json"
+	repo := state.Repository{
+		Name: "Nameless",
+		RepositoryOrigin: state.RepositoryOrigin{
+			RepositoryUrl: url,
+			LastUpdatedAt: time.Now().Unix(),
+		},
+	}
+
+	state.UpsertRepository(repo)
+	defer state.DeleteRepositoriesByOriginUrl(url)
+
+	removeRejectedRepositories(url, nil, []state.ValidationResult{{Index: 0, Rejected: true}})
+
+	if repos := state.GetRepositoriesByOriginUrl(url); len(repos) != 1 {
+		t.Errorf("Expected the existing entry to be kept, got %+v", repos)
+	}
+}
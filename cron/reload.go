@@ -0,0 +1,357 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/cron/jobs"
+	"github.com/senither/dalamud-plugin-listing/metrics"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// reloadDebounce is how long the watcher waits after the last file change
+// before reloading, editors often write a file in several steps.
+const reloadDebounce = time.Second
+
+var (
+	reloadMu sync.Mutex
+
+	// loadedFingerprint is a hash of the watched files when the config was
+	// last reloaded, the watcher skips changes that were already loaded, like
+	// the ones the admin API writes and reloads right away.
+	loadedFingerprint string
+
+	watcherMu sync.Mutex
+	watcher   *fsnotify.Watcher
+	hangupCh  chan os.Signal
+)
+
+// WatchConfig reloads the sources and plugins whenever the config file or the
+// legacy repositories.txt and plugins.txt files change, or when the process
+// receives a SIGHUP signal.
+func WatchConfig() {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+
+	hangupCh = make(chan os.Signal, 1)
+	signal.Notify(hangupCh, syscall.SIGHUP)
+
+	go func(signals chan os.Signal) {
+		for range signals {
+			slog.Info("Received SIGHUP, reloading the config")
+			Reload()
+		}
+	}(hangupCh)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("Failed to create the config file watcher, use SIGHUP to reload the config",
+			"err", err,
+		)
+		return
+	}
+
+	files := watchedFiles()
+
+	// The directories are watched rather than the files themselves, so files
+	// that are replaced by a rename or created later are still picked up.
+	var directories []string
+	for _, file := range files {
+		if dir := filepath.Dir(file); !slices.Contains(directories, dir) {
+			directories = append(directories, dir)
+		}
+	}
+
+	for _, dir := range directories {
+		if err := w.Add(dir); err != nil {
+			slog.Error("Failed to watch directory for config changes",
+				"err", err,
+				"directory", dir,
+			)
+		}
+	}
+
+	watcher = w
+
+	go func() {
+		var debounce *time.Timer
+
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+
+				if !slices.Contains(files, filepath.Clean(event.Name)) || event.Has(fsnotify.Chmod) {
+					continue
+				}
+
+				slog.Debug("Detected config file change",
+					"file", event.Name,
+					"op", event.Op.String(),
+				)
+
+				if debounce == nil {
+					debounce = time.AfterFunc(reloadDebounce, reloadIfChanged)
+				} else {
+					debounce.Reset(reloadDebounce)
+				}
+
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+
+				slog.Error("Config file watcher failed", "err", err)
+			}
+		}
+	}()
+}
+
+// StopWatchingConfig stops the file watcher and the SIGHUP handler.
+func StopWatchingConfig() {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+
+	if hangupCh != nil {
+		signal.Stop(hangupCh)
+		close(hangupCh)
+		hangupCh = nil
+	}
+
+	if watcher != nil {
+		watcher.Close()
+		watcher = nil
+	}
+}
+
+// Reload loads the config again and reconciles it with the running state,
+// jobs are started for new sources and plugins, removed ones are stopped and
+// purged, jobs with a changed interval are rescheduled, and plugins with
+// changed settings are updated in place. The current config is kept if the
+// new config fails to load.
+func Reload() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	reload()
+}
+
+// reloadIfChanged reloads the config unless the watched files are the same as
+// when the config was last reloaded.
+func reloadIfChanged() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if configFingerprint() == loadedFingerprint {
+		slog.Debug("Config files are unchanged since the last reload, skipping the reload")
+		return
+	}
+
+	reload()
+}
+
+func reload() {
+	fingerprint := configFingerprint()
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Failed to reload the config, keeping the current config",
+			"err", err,
+		)
+		return
+	}
+
+	loadedFingerprint = fingerprint
+
+	previous := config.Get()
+	config.Set(cfg)
+
+	var addedSources, removedSources, rescheduledSources []string
+
+	enabledSources := make(map[string]config.Source)
+	for _, source := range cfg.EnabledSources() {
+		enabledSources[source.Url] = source
+	}
+
+	for _, url := range state.GetUrls() {
+		if _, ok := enabledSources[url]; ok {
+			continue
+		}
+
+		jobs.StopUpdateRepositoryJob(url)
+		state.RemoveUrl(url)
+		state.DeleteRepositoriesByOriginUrl(url)
+		state.DeleteSourceStatus(url)
+		metrics.DeleteSourceMetrics(url)
+
+		removedSources = append(removedSources, url)
+	}
+
+	currentUrls := state.GetUrls()
+	for _, source := range cfg.EnabledSources() {
+		if slices.Contains(currentUrls, source.Url) {
+			if sourceIntervalChanged(previous, cfg, source.Url) {
+				rescheduledSources = append(rescheduledSources, source.Url)
+			}
+
+			continue
+		}
+
+		state.AddUrl(source.Url)
+		if !slices.Contains(state.GetUrls(), source.Url) {
+			slog.Warn("Skipping invalid source URL", "url", source.Url)
+			continue
+		}
+
+		addedSources = append(addedSources, source.Url)
+	}
+
+	var addedPlugins, removedPlugins, updatedPlugins, rescheduledPlugins []string
+
+	enabledPlugins := make(map[string]state.InternalPlugin)
+	for _, plugin := range cfg.EnabledPlugins() {
+		if ip, ok := plugin.InternalPlugin(); ok {
+			enabledPlugins[strings.ToLower(ip.Name)] = ip
+		}
+	}
+
+	for _, ip := range state.GetInternalPlugins() {
+		next, ok := enabledPlugins[strings.ToLower(ip.Name)]
+		if !ok {
+			jobs.StopUpdatePluginReleaseJob(ip.Name)
+			state.RemoveInternalPlugin(ip.Name)
+
+			removedPlugins = append(removedPlugins, ip.Name)
+			continue
+		}
+
+		delete(enabledPlugins, strings.ToLower(ip.Name))
+
+		if pluginIntervalChanged(previous, cfg, ip.Name) {
+			rescheduledPlugins = append(rescheduledPlugins, ip.Name)
+		}
+
+		if next == ip {
+			continue
+		}
+
+		// The release metadata is dropped so the next run rebuilds the plugin
+		// entry, otherwise unchanged releases would keep the old settings.
+		state.AddInternalPlugin(next)
+		state.DeleteReleaseMetadata(ip.Name)
+
+		updatedPlugins = append(updatedPlugins, ip.Name)
+	}
+
+	for _, ip := range enabledPlugins {
+		state.AddInternalPlugin(ip)
+
+		addedPlugins = append(addedPlugins, ip.Name)
+	}
+
+	slices.Sort(addedPlugins)
+
+	expiryRescheduled := previous.Global.ExpireCheckInterval != cfg.Global.ExpireCheckInterval
+
+	slog.Info("Reloaded the config",
+		"addedSources", addedSources,
+		"removedSources", removedSources,
+		"addedPlugins", addedPlugins,
+		"removedPlugins", removedPlugins,
+		"updatedPlugins", updatedPlugins,
+		"rescheduledSources", rescheduledSources,
+		"rescheduledPlugins", rescheduledPlugins,
+		"rescheduledExpiryCheck", expiryRescheduled,
+	)
+
+	for _, url := range addedSources {
+		jobs.StartUpdateRepositoryJob(url, cfg.Source(url).NextInterval(cfg.Global), true)
+	}
+
+	for _, repoName := range addedPlugins {
+		jobs.StartUpdatePluginReleaseJob(repoName, cfg.Plugin(repoName).NextInterval(cfg.Global), true)
+	}
+
+	for _, url := range rescheduledSources {
+		jobs.StartUpdateRepositoryJob(url, cfg.Source(url).NextInterval(cfg.Global), false)
+	}
+
+	for _, repoName := range rescheduledPlugins {
+		jobs.StartUpdatePluginReleaseJob(repoName, cfg.Plugin(repoName).NextInterval(cfg.Global), false)
+	}
+
+	for _, repoName := range updatedPlugins {
+		jobs.RunGitHubReleaseUpdateJob(repoName)
+	}
+
+	if expiryRescheduled {
+		jobs.StartDeleteExpiredRepositoriesJob(cfg.Global.ExpireCheckInterval.Duration())
+	}
+
+	if previous.Global.ApiLevel.Source != cfg.Global.ApiLevel.Source ||
+		previous.Global.ApiLevel.Interval != cfg.Global.ApiLevel.Interval {
+		jobs.StopUpdateDalamudApiLevelJob()
+
+		if cfg.Global.ApiLevel.Source != "" {
+			jobs.StartUpdateDalamudApiLevelJob(cfg.Global.ApiLevel.Source, cfg.Global.ApiLevel.Interval.Duration())
+		}
+	}
+}
+
+// sourceIntervalChanged returns true if the source is scheduled differently,
+// sources without their own interval use the global range.
+func sourceIntervalChanged(previous *config.Config, next *config.Config, url string) bool {
+	before, after := previous.Source(url).Interval, next.Source(url).Interval
+	if before != after {
+		return true
+	}
+
+	return after == 0 && previous.Global.SourceInterval != next.Global.SourceInterval
+}
+
+func pluginIntervalChanged(previous *config.Config, next *config.Config, name string) bool {
+	return previous.Plugin(name).NextInterval(previous.Global) != next.Plugin(name).NextInterval(next.Global)
+}
+
+// configFingerprint hashes the contents of the watched files, missing files
+// are hashed as empty.
+func configFingerprint() string {
+	hash := sha256.New()
+
+	for _, file := range watchedFiles() {
+		content, _ := os.ReadFile(file)
+
+		hash.Write([]byte(file))
+		hash.Write(content)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func watchedFiles() []string {
+	var files []string
+
+	for _, path := range []string{config.Path(), "repositories.txt", "plugins.txt"} {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, absolute)
+	}
+
+	return files
+}
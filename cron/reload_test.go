@@ -0,0 +1,66 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/config"
+)
+
+func TestIntervalChangesAreDetected(t *testing.T) {
+	url := "https://example.com/repo.json"
+
+	previous := config.Default()
+	previous.Sources = []config.Source{{Url: url}}
+	previous.Plugins = []config.Plugin{{Name: "Senither/Plugin"}}
+
+	next := config.Default()
+	next.Sources = []config.Source{{Url: url}}
+	next.Plugins = []config.Plugin{{Name: "Senither/Plugin"}}
+
+	if sourceIntervalChanged(previous, next, url) || pluginIntervalChanged(previous, next, "Senither/Plugin") {
+		t.Fatal("Expected no changes between identical configs")
+	}
+
+	next.Global.SourceInterval.Max = config.Duration(2 * time.Hour)
+	next.Plugins[0].Interval = config.Duration(time.Hour)
+
+	if !sourceIntervalChanged(previous, next, url) {
+		t.Error("Expected a changed global range to reschedule sources without their own interval")
+	}
+
+	if !pluginIntervalChanged(previous, next, "Senither/Plugin") {
+		t.Error("Expected a changed plugin interval to be detected")
+	}
+
+	previous.Sources[0].Interval = config.Duration(time.Hour)
+	next.Sources[0].Interval = config.Duration(time.Hour)
+
+	if sourceIntervalChanged(previous, next, url) {
+		t.Error("Expected a source with its own interval to ignore the global range")
+	}
+}
+
+func TestConfigFingerprintFollowsTheFileContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	t.Setenv("APP_CONFIG", path)
+
+	if err := os.WriteFile(path, []byte("sources: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := configFingerprint()
+	if configFingerprint() != fingerprint {
+		t.Fatal("Expected the fingerprint of unchanged files to stay the same")
+	}
+
+	if err := os.WriteFile(path, []byte("sources:\n  - url: https://example.com/repo.json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if configFingerprint() == fingerprint {
+		t.Error("Expected an edited config file to change the fingerprint")
+	}
+}
@@ -0,0 +1,331 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownJob = errors.New("scheduler: unknown job")
+	ErrShutdown   = errors.New("scheduler: shut down")
+)
+
+// Func is the work done by a job, the context is cancelled when the job is
+// stopped or when the scheduler is forced to shut down.
+type Func func(ctx context.Context) error
+
+// Status is the state of a job and the outcome of its most recent run.
+type Status struct {
+	Name        string
+	Interval    time.Duration
+	Running     bool
+	Runs        int
+	LastRun     time.Time
+	LastSuccess time.Time
+	LastError   string
+	NextRun     time.Time
+	Duration    time.Duration
+}
+
+// Scheduler runs jobs on an interval, every run gets a context that is
+// cancelled when the job is stopped, and in-flight runs are tracked so the
+// scheduler can wait for them to finish when shutting down.
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg sync.WaitGroup
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+
+	// runMu makes sure a job never runs concurrently with itself, like when
+	// it's triggered manually while a scheduled run is in progress. It's
+	// shared with the job that replaces it, so the old run finishes first.
+	runMu *sync.Mutex
+
+	statusMu sync.RWMutex
+	status   Status
+}
+
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*job),
+	}
+}
+
+// Schedule starts running the job every interval, replacing any job that is
+// already scheduled with the same name. The replaced job is cancelled like
+// when it's stopped, and the new job doesn't run until its run has returned.
+// The first run happens immediately if runOnStart is set, otherwise after the
+// first interval.
+func (s *Scheduler) Schedule(name string, interval time.Duration, runOnStart bool, fn Func) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrShutdown
+	}
+
+	runMu := &sync.Mutex{}
+
+	if existing, ok := s.jobs[name]; ok {
+		existing.halt()
+		existing.cancel()
+
+		runMu = existing.runMu
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	j := &job{
+		name:     name,
+		interval: interval,
+		fn:       fn,
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		runMu:    runMu,
+		status: Status{
+			Name:     name,
+			Interval: interval,
+		},
+	}
+
+	s.jobs[name] = j
+
+	s.wg.Add(1)
+	go s.loop(j, runOnStart)
+
+	return nil
+}
+
+// Stop stops scheduling the job and cancels the run in progress, if any.
+func (s *Scheduler) Stop(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return false
+	}
+
+	j.halt()
+	j.cancel()
+	delete(s.jobs, name)
+
+	return true
+}
+
+// Trigger runs the job immediately and waits for it to finish, the regular
+// schedule of the job is left untouched.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		return ErrShutdown
+	}
+
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrUnknownJob
+	}
+
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	defer s.wg.Done()
+
+	return s.run(j)
+}
+
+// Go runs a one-off task in the background, the task is tracked like a job
+// run so shutting down waits for it or cancels it.
+func (s *Scheduler) Go(name string, fn Func) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrShutdown
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		if err := fn(s.ctx); err != nil {
+			slog.Error("Background task failed",
+				"task", name,
+				"err", err,
+			)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) Status(name string) (Status, bool) {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return Status{}, false
+	}
+
+	return j.snapshot(), true
+}
+
+// Statuses returns the status of every scheduled job, keyed by job name.
+func (s *Scheduler) Statuses() map[string]Status {
+	s.mu.Lock()
+	jobs := maps.Clone(s.jobs)
+	s.mu.Unlock()
+
+	statuses := make(map[string]Status, len(jobs))
+	for name, j := range jobs {
+		statuses[name] = j.snapshot()
+	}
+
+	return statuses
+}
+
+// Shutdown stops scheduling new runs and waits for the runs in progress to
+// finish. If the context expires first the runs are cancelled, and the
+// context error is returned once they've returned.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for _, j := range s.jobs {
+		j.halt()
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.cancel()
+		return nil
+
+	case <-ctx.Done():
+		slog.Warn("Jobs did not finish in time, cancelling them")
+
+		s.cancel()
+		<-drained
+
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(j *job, runOnStart bool) {
+	defer s.wg.Done()
+
+	if runOnStart {
+		select {
+		case <-j.stop:
+			return
+		default:
+			s.run(j)
+		}
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	j.setNextRun(time.Now().Add(j.interval))
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(j)
+			j.setNextRun(time.Now().Add(j.interval))
+		}
+	}
+}
+
+func (s *Scheduler) run(j *job) error {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	if err := j.ctx.Err(); err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+
+	j.statusMu.Lock()
+	j.status.Running = true
+	j.status.LastRun = startedAt
+	j.statusMu.Unlock()
+
+	err := j.fn(j.ctx)
+
+	j.statusMu.Lock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.Duration = time.Since(startedAt)
+
+	if err != nil {
+		j.status.LastError = err.Error()
+	} else {
+		j.status.LastSuccess = startedAt
+		j.status.LastError = ""
+	}
+	j.statusMu.Unlock()
+
+	return err
+}
+
+// halt stops the job from being scheduled again without cancelling the run
+// in progress, it's safe to call multiple times.
+func (j *job) halt() {
+	select {
+	case <-j.stop:
+	default:
+		close(j.stop)
+	}
+}
+
+func (j *job) setNextRun(next time.Time) {
+	j.statusMu.Lock()
+	j.status.NextRun = next
+	j.statusMu.Unlock()
+}
+
+func (j *job) snapshot() Status {
+	j.statusMu.RLock()
+	defer j.statusMu.RUnlock()
+
+	return j.status
+}
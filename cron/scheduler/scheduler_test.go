@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduleRunsOnStartAndInterval(t *testing.T) {
+	s := New()
+	defer s.Shutdown(context.Background())
+
+	var runs atomic.Int32
+	s.Schedule("job", 10*time.Millisecond, true, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	waitFor(t, func() bool { return runs.Load() >= 3 })
+
+	status, ok := s.Status("job")
+	if !ok || status.LastSuccess.IsZero() || status.NextRun.IsZero() || status.Interval != 10*time.Millisecond {
+		t.Errorf("Expected the status to be tracked, got %+v", status)
+	}
+}
+
+func TestTriggerRecordsErrors(t *testing.T) {
+	s := New()
+	defer s.Shutdown(context.Background())
+
+	failure := errors.New("upstream is down")
+	s.Schedule("job", time.Hour, false, func(ctx context.Context) error {
+		return failure
+	})
+
+	if err := s.Trigger("job"); !errors.Is(err, failure) {
+		t.Fatalf("Expected the job error to be returned, got %v", err)
+	}
+
+	if status, _ := s.Status("job"); status.LastError != failure.Error() || status.Runs != 1 {
+		t.Errorf("Expected the error to be recorded, got %+v", status)
+	}
+
+	if err := s.Trigger("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Expected an unknown job error, got %v", err)
+	}
+}
+
+func TestStopCancelsRunningJob(t *testing.T) {
+	s := New()
+	defer s.Shutdown(context.Background())
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	s.Schedule("job", time.Hour, true, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+
+		return ctx.Err()
+	})
+
+	<-started
+
+	if !s.Stop("job") {
+		t.Fatal("Expected the job to be stopped")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the running job to be cancelled")
+	}
+
+	if _, ok := s.Status("job"); ok {
+		t.Error("Expected the stopped job to be removed")
+	}
+}
+
+func TestRescheduleCancelsRunningJob(t *testing.T) {
+	s := New()
+	defer s.Shutdown(context.Background())
+
+	started := make(chan struct{})
+	var previous context.Context
+	var overlapped atomic.Bool
+	var running atomic.Bool
+
+	s.Schedule("job", time.Hour, true, func(ctx context.Context) error {
+		previous = ctx
+		running.Store(true)
+		close(started)
+
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		running.Store(false)
+
+		return ctx.Err()
+	})
+
+	<-started
+
+	var runs atomic.Int32
+	s.Schedule("job", time.Hour, true, func(ctx context.Context) error {
+		overlapped.Store(running.Load())
+		runs.Add(1)
+
+		return nil
+	})
+
+	waitFor(t, func() bool { return runs.Load() == 1 })
+
+	if previous.Err() == nil {
+		t.Error("Expected the context of the replaced job to be cancelled")
+	}
+
+	if overlapped.Load() {
+		t.Error("Expected the new job to wait for the replaced run to return")
+	}
+}
+
+func TestShutdownWaitsForRunningJobs(t *testing.T) {
+	s := New()
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+
+	s.Schedule("job", time.Hour, true, func(ctx context.Context) error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+
+		return nil
+	})
+
+	<-started
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+
+	if !finished.Load() {
+		t.Error("Expected shutdown to wait for the running job")
+	}
+
+	if err := s.Schedule("late", time.Hour, true, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected scheduling after shutdown to fail, got %v", err)
+	}
+}
+
+func TestShutdownCancelsJobsAfterDeadline(t *testing.T) {
+	s := New()
+
+	started := make(chan struct{})
+	s.Schedule("job", time.Hour, true, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown deadline to be exceeded, got %v", err)
+	}
+}
@@ -1,20 +1,24 @@
 package cron
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
-	"math/rand/v2"
+	"os"
 	"time"
 
+	"github.com/senither/dalamud-plugin-listing/config"
 	"github.com/senither/dalamud-plugin-listing/cron/jobs"
 	"github.com/senither/dalamud-plugin-listing/state"
 )
 
 func SetupJobs() {
-	state.LoadCachedRepositoryDataFromDisk()
-	state.LoadRepositoriesFromDisk()
-	state.LoadPluginsFromDisk()
+	if err := LoadState(); err != nil {
+		slog.Error("Failed to load the state", "err", err)
+		os.Exit(1)
+	}
 
-	state.LoadCachedPluginReleasesDataFromDisk()
+	cfg := config.Get()
 
 	// Loops through all the repositories in the state and creates a new job for each one.
 	for _, repoUrl := range state.GetUrls() {
@@ -28,10 +32,10 @@ func SetupJobs() {
 			}
 		}
 
-		// Gets a random number between 55 and 70 to add some randomness to
-		// the job delay, then starts the job with the specified values.
-		jobDelay := rand.IntN(15) + 55
-		jobs.StartUpdateRepositoryJob(repoUrl, time.Minute*time.Duration(jobDelay), runOnStart)
+		// Sources without their own interval get a random interval within the
+		// configured range, this spreads the jobs out so they don't all run at once.
+		jobDelay := cfg.Source(repoUrl).NextInterval(cfg.Global)
+		jobs.StartUpdateRepositoryJob(repoUrl, jobDelay, runOnStart)
 	}
 
 	for _, internalPlugin := range state.GetInternalPlugins() {
@@ -42,22 +46,77 @@ func SetupJobs() {
 			runOnStart = repo.RepositoryOrigin.LastUpdatedAt <= time.Now().Add(time.Minute*120*-1).Unix()
 		}
 
-		jobs.StartUpdatePluginReleaseJob(internalPlugin.Name, time.Minute*time.Duration(60*12), runOnStart)
+		jobDelay := cfg.Plugin(internalPlugin.Name).NextInterval(cfg.Global)
+		jobs.StartUpdatePluginReleaseJob(internalPlugin.Name, jobDelay, runOnStart)
+	}
+
+	jobs.StartDeleteExpiredRepositoriesJob(cfg.Global.ExpireCheckInterval.Duration())
+
+	if cfg.Global.ApiLevel.Source != "" {
+		jobs.StartUpdateDalamudApiLevelJob(cfg.Global.ApiLevel.Source, cfg.Global.ApiLevel.Interval.Duration())
+	}
+
+	WatchConfig()
+}
+
+// FetchOnce loads the cached state and updates every source and internal
+// plugin once without starting any jobs, the state is written to the store
+// before returning. The failed updates are returned keyed by the source URL
+// or plugin name.
+func FetchOnce(ctx context.Context, concurrency int) (map[string]error, error) {
+	if err := LoadState(); err != nil {
+		return nil, err
 	}
 
-	jobs.StartDeleteExpiredRepositoriesJob(time.Second * 30)
+	failures := jobs.RunOnce(ctx, concurrency)
+
+	return failures, state.CloseStore()
 }
 
-func ShutdownJobs() {
-	for url, job := range jobs.GetRepositoryJobs() {
-		slog.Debug("Shutting down job", "url", url)
+// LoadState opens the store and loads the config, then loads the cached
+// plugins and releases for the enabled sources and plugins.
+func LoadState() error {
+	if err := state.OpenStore(); err != nil {
+		return fmt.Errorf("failed to open the state store: %w", err)
+	}
 
-		job.Ticker.Stop()
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load the config: %w", err)
 	}
 
-	for repoName, job := range jobs.GetPluginReleasesJobs() {
-		slog.Debug("Shutting down job", "repoName", repoName)
+	config.Set(cfg)
+
+	state.LoadCachedRepositoryDataFromDisk()
+
+	for _, source := range cfg.EnabledSources() {
+		state.AddUrl(source.Url)
+	}
+
+	for _, plugin := range cfg.EnabledPlugins() {
+		if ip, ok := plugin.InternalPlugin(); ok {
+			state.AddInternalPlugin(ip)
+		}
+	}
+
+	state.LoadCachedPluginReleasesDataFromDisk()
+
+	return nil
+}
+
+// ShutdownJobs stops the jobs and waits for the jobs in progress to finish,
+// jobs that are still running after the timeout are cancelled.
+func ShutdownJobs(timeout time.Duration) {
+	StopWatchingConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := jobs.Shutdown(ctx); err != nil {
+		slog.Warn("Cancelled jobs that did not finish in time", "err", err)
+	}
 
-		job.Ticker.Stop()
+	if err := state.CloseStore(); err != nil {
+		slog.Error("Failed to close the state store", "err", err)
 	}
 }
@@ -0,0 +1,107 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// giteaProvider handles both Gitea and Forgejo, Forgejo is a fork of Gitea
+// and still exposes the same release API.
+type giteaProvider struct{}
+
+type giteaRelease struct {
+	Url         string `json:"url"`
+	TagName     string `json:"tag_name"`
+	Draft       bool   `json:"draft"`
+	Prerelease  bool   `json:"prerelease"`
+	Body        string `json:"body"`
+	CreatedAt   string `json:"created_at"`
+	PublishedAt string `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		DownloadCount      int    `json:"download_count"`
+		BrowserDownloadUrl string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (p giteaProvider) ListReleases(ctx context.Context, ip state.InternalPlugin) ([]state.GitHubPluginRelease, error) {
+	next := fmt.Sprintf("https://%s/api/v1/repos/%s/releases?limit=50", ip.Host, ip.Name)
+
+	var releases []state.GitHubPluginRelease
+
+	for page := 0; next != "" && page < maxPages; page++ {
+		var giteaReleases []giteaRelease
+
+		link, err := getJson(ctx, next, p.headers(ip), &giteaReleases)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range giteaReleases {
+			releases = append(releases, release.toGitHubRelease())
+		}
+
+		next = nextPageUrl(link)
+	}
+
+	return releases, nil
+}
+
+func (p giteaProvider) DownloadAsset(ctx context.Context, ip state.InternalPlugin, assetUrl string) (*http.Response, error) {
+	return download(ctx, assetUrl, p.headers(ip))
+}
+
+func (giteaProvider) HasToken(ip state.InternalPlugin) bool {
+	return giteaToken(ip) != ""
+}
+
+func (giteaProvider) headers(ip state.InternalPlugin) map[string]string {
+	token := giteaToken(ip)
+	if token == "" || !ip.Private {
+		return nil
+	}
+
+	return map[string]string{"Authorization": "token " + token}
+}
+
+func giteaToken(ip state.InternalPlugin) string {
+	if ip.Provider == state.ProviderForgejo {
+		return os.Getenv("FORGEJO_TOKEN")
+	}
+
+	return os.Getenv("GITEA_TOKEN")
+}
+
+func (r giteaRelease) toGitHubRelease() state.GitHubPluginRelease {
+	createdAt := r.PublishedAt
+	if createdAt == "" {
+		createdAt = r.CreatedAt
+	}
+
+	release := state.GitHubPluginRelease{
+		Url:        r.Url,
+		TagName:    r.TagName,
+		Draft:      r.Draft,
+		Prerelease: r.Prerelease,
+		Body:       r.Body,
+		CreatedAt:  createdAt,
+	}
+
+	// The asset API URL only returns metadata on Gitea, so the browser
+	// download URL is used for both, it accepts the token for private repos.
+	for _, asset := range r.Assets {
+		release.Assets = append(release.Assets, state.GitHubPluginReleaseAsset{
+			Url:                asset.BrowserDownloadUrl,
+			Name:               asset.Name,
+			ContentType:        contentTypeFromName(asset.Name),
+			BrowserDownloadUrl: asset.BrowserDownloadUrl,
+			DownloadCount:      asset.DownloadCount,
+		})
+	}
+
+	return release
+}
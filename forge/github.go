@@ -0,0 +1,23 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/senither/dalamud-plugin-listing/github"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type gitHubProvider struct{}
+
+func (gitHubProvider) ListReleases(ctx context.Context, ip state.InternalPlugin) ([]state.GitHubPluginRelease, error) {
+	return github.Default().ListReleases(ctx, ip.Name)
+}
+
+func (gitHubProvider) DownloadAsset(ctx context.Context, ip state.InternalPlugin, assetUrl string) (*http.Response, error) {
+	return github.Default().DownloadAsset(ctx, assetUrl)
+}
+
+func (gitHubProvider) HasToken(ip state.InternalPlugin) bool {
+	return github.Default().Token != ""
+}
@@ -0,0 +1,136 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type gitLabProvider struct{}
+
+type gitLabRelease struct {
+	TagName         string `json:"tag_name"`
+	Description     string `json:"description"`
+	CreatedAt       string `json:"created_at"`
+	ReleasedAt      string `json:"released_at"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Assets          struct {
+		Links []struct {
+			Name           string `json:"name"`
+			Url            string `json:"url"`
+			DirectAssetUrl string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+	Links struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+func (p gitLabProvider) ListReleases(ctx context.Context, ip state.InternalPlugin) ([]state.GitHubPluginRelease, error) {
+	next := fmt.Sprintf("https://%s/api/v4/projects/%s/releases?per_page=100", ip.Host, url.PathEscape(ip.Name))
+
+	var releases []state.GitHubPluginRelease
+
+	for page := 0; next != "" && page < maxPages; page++ {
+		var gitLabReleases []gitLabRelease
+
+		link, err := getJson(ctx, next, p.headers(ip), &gitLabReleases)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range gitLabReleases {
+			releases = append(releases, release.toGitHubRelease())
+		}
+
+		next = nextPageUrl(link)
+	}
+
+	return releases, nil
+}
+
+func (p gitLabProvider) DownloadAsset(ctx context.Context, ip state.InternalPlugin, assetUrl string) (*http.Response, error) {
+	return download(ctx, assetUrl, p.headers(ip))
+}
+
+func (gitLabProvider) HasToken(ip state.InternalPlugin) bool {
+	return os.Getenv("GITLAB_TOKEN") != ""
+}
+
+func (gitLabProvider) headers(ip state.InternalPlugin) map[string]string {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" || !ip.Private {
+		return nil
+	}
+
+	return map[string]string{"PRIVATE-TOKEN": token}
+}
+
+func (r gitLabRelease) toGitHubRelease() state.GitHubPluginRelease {
+	createdAt := r.ReleasedAt
+	if createdAt == "" {
+		createdAt = r.CreatedAt
+	}
+
+	release := state.GitHubPluginRelease{
+		Url:        r.Links.Self,
+		TagName:    r.TagName,
+		Prerelease: r.UpcomingRelease,
+		Body:       r.Description,
+		CreatedAt:  createdAt,
+	}
+
+	for _, link := range r.Assets.Links {
+		downloadUrl := link.DirectAssetUrl
+		if downloadUrl == "" {
+			downloadUrl = link.Url
+		}
+
+		release.Assets = append(release.Assets, state.GitHubPluginReleaseAsset{
+			Url:                downloadUrl,
+			Name:               link.Name,
+			ContentType:        contentTypeFromName(link.Name),
+			BrowserDownloadUrl: downloadUrl,
+		})
+	}
+
+	return release
+}
+
+// getJson decodes the JSON response from the given URL into the value and
+// returns the Link header so the caller can follow the pagination.
+func getJson(ctx context.Context, requestUrl string, headers map[string]string, value any) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &Error{StatusCode: resp.StatusCode, Url: requestUrl}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+		return "", err
+	}
+
+	return resp.Header.Get("Link"), nil
+}
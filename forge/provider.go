@@ -0,0 +1,112 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// Provider maps the release and asset APIs of a forge onto the GitHub release
+// types stored in the state, so the rest of the application doesn't need to
+// know where an internal plugin is published.
+type Provider interface {
+	// ListReleases returns the releases for the plugin, newest first.
+	ListReleases(ctx context.Context, ip state.InternalPlugin) ([]state.GitHubPluginRelease, error)
+	// DownloadAsset requests an asset URL from one of the plugin releases,
+	// authenticating the request with the provider token if one is set.
+	DownloadAsset(ctx context.Context, ip state.InternalPlugin, assetUrl string) (*http.Response, error)
+	// HasToken reports if a token is configured for private repositories.
+	HasToken(ip state.InternalPlugin) bool
+}
+
+const (
+	userAgent = "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)"
+	maxPages  = 10
+)
+
+var (
+	httpClient    = &http.Client{Timeout: 60 * time.Second}
+	linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
+
+// For returns the provider the given internal plugin is published on.
+func For(ip state.InternalPlugin) (Provider, error) {
+	switch ip.Provider {
+	case state.ProviderGitHub, "":
+		return gitHubProvider{}, nil
+	case state.ProviderGitLab:
+		return gitLabProvider{}, nil
+	case state.ProviderGitea, state.ProviderForgejo:
+		return giteaProvider{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown provider %q for %s", ip.Provider, ip.Name)
+}
+
+// Error is returned when a forge responds with an unexpected status.
+type Error struct {
+	StatusCode int
+	Url        string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("forge: unexpected status %d from %s", e.StatusCode, e.Url)
+}
+
+func nextPageUrl(link string) string {
+	matches := linkNextRegex.FindStringSubmatch(link)
+	if len(matches) != 2 {
+		return ""
+	}
+
+	return matches[1]
+}
+
+// contentTypeFromName guesses the content type of an asset from its file
+// name, GitHub reports it for every asset but the other forges don't.
+func contentTypeFromName(name string) string {
+	switch path.Ext(name) {
+	case ".json":
+		return "application/json"
+	case ".zip":
+		return "application/zip"
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+func download(ctx context.Context, assetUrl string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/octet-stream")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, &Error{StatusCode: resp.StatusCode, Url: assetUrl}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,146 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestParseInternalPluginProviders(t *testing.T) {
+	cases := map[string]state.InternalPlugin{
+		"Senither/Plugin":                     {Name: "Senither/Plugin", Provider: state.ProviderGitHub, Host: "github.com"},
+		"P:Senither/Plugin":                   {Name: "Senither/Plugin", Provider: state.ProviderGitHub, Host: "github.com", Private: true},
+		"gitlab:group/sub/project":            {Name: "group/sub/project", Provider: state.ProviderGitLab, Host: "gitlab.com"},
+		"P:gitlab:gitlab.example.com/group/p": {Name: "group/p", Provider: state.ProviderGitLab, Host: "gitlab.example.com", Private: true},
+		"forgejo:codeberg.org/owner/repo":     {Name: "owner/repo", Provider: state.ProviderForgejo, Host: "codeberg.org"},
+		"gitea:owner/repo":                    {Name: "owner/repo", Provider: state.ProviderGitea, Host: "gitea.com"},
+	}
+
+	for entry, expected := range cases {
+		ip, ok := state.ParseInternalPlugin(entry)
+		if !ok || ip != expected {
+			t.Errorf("Expected %q to parse as %+v, got %+v (%v)", entry, expected, ip, ok)
+		}
+	}
+
+	for _, entry := range []string{"unknown:owner/repo", "gitlab:project", "P:"} {
+		if ip, ok := state.ParseInternalPlugin(entry); ok {
+			t.Errorf("Expected %q to be rejected, got %+v", entry, ip)
+		}
+	}
+}
+
+func TestGitLabReleasesAreMappedToGitHubReleases(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			t.Errorf("Expected the private token to be sent, got %q", r.Header.Get("PRIVATE-TOKEN"))
+		}
+
+		if !strings.HasPrefix(r.URL.EscapedPath(), "/api/v4/projects/group%2Fproject/releases") {
+			t.Errorf("Expected the project path to be escaped, got %s", r.URL.EscapedPath())
+		}
+
+		fmt.Fprintf(w, `[{
+			"tag_name": "v1.0.0",
+			"description": "Changelog",
+			"released_at": "2024-01-01T00:00:00Z",
+			"assets": {"links": [
+				{"name": "Plugin.json", "url": "%[1]s/manifest", "direct_asset_url": "%[1]s/direct/manifest"},
+				{"name": "latest.zip", "url": "%[1]s/latest.zip"}
+			]},
+			"_links": {"self": "%[1]s/releases/v1.0.0"}
+		}]`, server.URL)
+	}))
+	defer server.Close()
+
+	t.Setenv("GITLAB_TOKEN", "secret")
+
+	ip := state.InternalPlugin{
+		Name:     "group/project",
+		Provider: state.ProviderGitLab,
+		Host:     strings.TrimPrefix(server.URL, "http://"),
+		Private:  true,
+	}
+
+	releases, err := listReleasesOverHttp(t, gitLabProvider{}, ip)
+	if err != nil {
+		t.Fatalf("Expected releases to be listed, got %v", err)
+	}
+
+	if len(releases) != 1 || releases[0].TagName != "v1.0.0" || releases[0].CreatedAt != "2024-01-01T00:00:00Z" {
+		t.Fatalf("Expected the release to be mapped, got %+v", releases)
+	}
+
+	manifest, latest := state.GetManifestAndLatestReleaseAssets(releases[0])
+	if manifest == nil || manifest.BrowserDownloadUrl != server.URL+"/direct/manifest" {
+		t.Errorf("Expected the manifest asset to use the direct URL, got %+v", manifest)
+	}
+
+	if latest == nil || latest.Url != server.URL+"/latest.zip" {
+		t.Errorf("Expected the release archive to be found, got %+v", latest)
+	}
+}
+
+func TestGiteaReleasesAreMappedToGitHubReleases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no token for public repositories, got %q", r.Header.Get("Authorization"))
+		}
+
+		fmt.Fprint(w, `[{
+			"tag_name": "v2",
+			"body": "Changes",
+			"prerelease": true,
+			"published_at": "2024-02-01T00:00:00Z",
+			"assets": [{"name": "latest.zip", "download_count": 12, "browser_download_url": "https://codeberg.org/latest.zip"}]
+		}]`)
+	}))
+	defer server.Close()
+
+	t.Setenv("FORGEJO_TOKEN", "secret")
+
+	ip := state.InternalPlugin{
+		Name:     "owner/repo",
+		Provider: state.ProviderForgejo,
+		Host:     strings.TrimPrefix(server.URL, "http://"),
+	}
+
+	releases, err := listReleasesOverHttp(t, giteaProvider{}, ip)
+	if err != nil {
+		t.Fatalf("Expected releases to be listed, got %v", err)
+	}
+
+	if len(releases) != 1 || !releases[0].Prerelease || releases[0].Assets[0].DownloadCount != 12 {
+		t.Errorf("Expected the release to be mapped, got %+v", releases)
+	}
+}
+
+// listReleasesOverHttp lists the releases through a transport that rewrites
+// the HTTPS requests made by the providers to the plain HTTP test server.
+func listReleasesOverHttp(t *testing.T, provider Provider, ip state.InternalPlugin) ([]state.GitHubPluginRelease, error) {
+	t.Helper()
+
+	previous := httpClient.Transport
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = "http"
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	t.Cleanup(func() {
+		httpClient.Transport = previous
+	})
+
+	return provider.ListReleases(context.Background(), ip)
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
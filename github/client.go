@@ -0,0 +1,393 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/senither/dalamud-plugin-listing/metrics"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+const (
+	DefaultBaseUrl = "https://api.github.com"
+	UserAgent      = "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)"
+)
+
+var ErrRateLimited = errors.New("github: rate limit exceeded")
+
+// Error is returned when the GitHub API responds with an unexpected status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("github: unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// RateLimit is the rate limit state reported by the most recent response.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Client is a GitHub API client that keeps track of the rate limit, retries
+// transient failures and follows pagination links.
+type Client struct {
+	// BaseUrl is the API root, it's only changed in tests.
+	BaseUrl string
+	// Token is sent as a bearer token with every request if set.
+	Token string
+	// MaxRetries is the number of times a transient failure is retried.
+	MaxRetries int
+	// RetryDelay is the base delay used for the exponential backoff.
+	RetryDelay time.Duration
+	// MaxPages is the maximum number of pages followed when listing.
+	MaxPages int
+	// RateLimitReserve is the number of requests background calls leave
+	// untouched, so downloads keep working when the limit is nearly used up.
+	RateLimitReserve int
+	// MaxRateLimitWait is the longest a request waits for the rate limit to
+	// reset, requests that would wait longer fail with ErrRateLimited.
+	MaxRateLimitWait time.Duration
+
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	rateLimit RateLimit
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+	linkNextRegex     = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
+
+func NewClient(token string) *Client {
+	return &Client{
+		BaseUrl:          DefaultBaseUrl,
+		Token:            token,
+		MaxRetries:       3,
+		RetryDelay:       time.Second,
+		MaxPages:         10,
+		RateLimitReserve: 25,
+		MaxRateLimitWait: time.Minute,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+				MaxIdleConnsPerHost:   10,
+			},
+		},
+	}
+}
+
+// Default returns the shared client, authenticated with GITHUB_TOKEN if set.
+func Default() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewClient(os.Getenv("GITHUB_TOKEN"))
+	})
+
+	return defaultClient
+}
+
+// RateLimit returns the rate limit reported by the most recent response.
+func (c *Client) RateLimit() RateLimit {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.rateLimit
+}
+
+// Do sends the request, retrying transient failures. Only requests without a
+// body are supported since the request may be sent multiple times.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.do(req, 0)
+}
+
+// ListReleases returns the releases for the given "owner/repo" repository,
+// newest first, following the pagination links up to MaxPages pages.
+func (c *Client) ListReleases(ctx context.Context, repoName string) ([]state.GitHubPluginRelease, error) {
+	next := fmt.Sprintf("%s/repos/%s/releases?per_page=100", strings.TrimSuffix(c.BaseUrl, "/"), repoName)
+
+	var releases []state.GitHubPluginRelease
+
+	for page := 0; next != "" && page < c.MaxPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := c.do(req, c.RateLimitReserve)
+		if err != nil {
+			return nil, err
+		}
+
+		pageReleases, err := decodeReleases(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		releases = append(releases, pageReleases...)
+		next = nextPageUrl(resp.Header.Get("Link"))
+	}
+
+	return releases, nil
+}
+
+// DownloadAsset requests the given release asset URL, the caller is
+// responsible for closing the response body.
+func (c *Client) DownloadAsset(ctx context.Context, assetUrl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/octet-stream")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) do(req *http.Request, reserve int) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(ctx, reserve); err != nil {
+			metrics.IncrementGitHubRequestCounter(metrics.GitHubRequestRateLimited)
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(c.prepare(req))
+
+		var delay time.Duration
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.MaxRetries {
+				metrics.IncrementGitHubRequestCounter(metrics.GitHubRequestFailed)
+				return nil, err
+			}
+
+			delay = c.backoff(attempt)
+		} else {
+			c.updateRateLimit(resp.Header)
+
+			retry, retryDelay := c.shouldRetry(resp, attempt)
+			if !retry {
+				if resp.StatusCode >= 200 && resp.StatusCode <= 399 {
+					metrics.IncrementGitHubRequestCounter(metrics.GitHubRequestSuccess)
+				} else {
+					metrics.IncrementGitHubRequestCounter(metrics.GitHubRequestFailed)
+				}
+
+				return resp, nil
+			}
+
+			delay = retryDelay
+
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 8<<10))
+			resp.Body.Close()
+		}
+
+		metrics.IncrementGitHubRequestCounter(metrics.GitHubRequestRetried)
+
+		slog.Warn("Retrying GitHub API request",
+			"url", req.URL.String(),
+			"attempt", attempt+1,
+			"delay", delay,
+			"err", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) prepare(req *http.Request) *http.Request {
+	prepared := req.Clone(req.Context())
+
+	if prepared.Header.Get("User-Agent") == "" {
+		prepared.Header.Set("User-Agent", UserAgent)
+	}
+
+	if c.Token != "" && prepared.Header.Get("Authorization") == "" {
+		prepared.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	return prepared
+}
+
+// shouldRetry decides if a response should be retried and how long to wait,
+// server errors are retried with backoff while rate limited responses are
+// retried once the limit resets, as long as that is within MaxRateLimitWait.
+func (c *Client) shouldRetry(resp *http.Response, attempt int) (bool, time.Duration) {
+	if attempt >= c.MaxRetries {
+		return false, 0
+	}
+
+	if resp.StatusCode >= 500 {
+		if delay, ok := retryAfter(resp.Header); ok {
+			return true, delay
+		}
+
+		return true, c.backoff(attempt)
+	}
+
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden &&
+			(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""))
+
+	if !rateLimited {
+		return false, 0
+	}
+
+	delay, ok := retryAfter(resp.Header)
+	if !ok {
+		delay = time.Until(c.RateLimit().Reset)
+	}
+
+	if delay > c.MaxRateLimitWait {
+		return false, 0
+	}
+
+	return true, max(delay, c.backoff(attempt))
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.RetryDelay << attempt
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay + rand.N(delay/2+1)
+}
+
+func (c *Client) waitForRateLimit(ctx context.Context, reserve int) error {
+	rateLimit := c.RateLimit()
+	if rateLimit.Limit == 0 || rateLimit.Remaining > reserve {
+		return nil
+	}
+
+	wait := time.Until(rateLimit.Reset)
+	if wait <= 0 {
+		return nil
+	}
+
+	if wait > c.MaxRateLimitWait {
+		return fmt.Errorf("%w, resets at %s", ErrRateLimited, rateLimit.Reset.Format(time.RFC3339))
+	}
+
+	slog.Warn("Waiting for the GitHub API rate limit to reset",
+		"remaining", rateLimit.Remaining,
+		"wait", wait,
+	)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func (c *Client) updateRateLimit(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	rateLimit := RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+
+	c.mu.Lock()
+	c.rateLimit = rateLimit
+	c.mu.Unlock()
+
+	metrics.SetGitHubRateLimit(rateLimit.Limit, rateLimit.Remaining, rateLimit.Reset)
+}
+
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+func nextPageUrl(link string) string {
+	matches := linkNextRegex.FindStringSubmatch(link)
+	if len(matches) != 2 {
+		return ""
+	}
+
+	return matches[1]
+}
+
+func decodeReleases(resp *http.Response) ([]state.GitHubPluginRelease, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(resp)
+	}
+
+	var releases []state.GitHubPluginRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+func newError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
+
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+}
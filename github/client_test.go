@@ -0,0 +1,184 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(server *httptest.Server) *Client {
+	client := NewClient("test-token")
+	client.BaseUrl = server.URL
+	client.RetryDelay = time.Millisecond
+
+	return client
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+func TestListReleasesFollowsPagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Expected the token to be sent, got %q", r.Header.Get("Authorization"))
+		}
+
+		setRateLimitHeaders(w, 4999, time.Now().Add(time.Hour))
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/releases?per_page=100&page=2>; rel="next", <%s/repos/owner/repo/releases?per_page=100&page=2>; rel="last"`, server.URL, server.URL))
+			fmt.Fprint(w, `[{"tag_name": "v2"}]`)
+		case "2":
+			fmt.Fprint(w, `[{"tag_name": "v1"}]`)
+		}
+	}))
+	defer server.Close()
+
+	releases, err := newTestClient(server).ListReleases(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("Expected releases to be listed, got %v", err)
+	}
+
+	if len(releases) != 2 || releases[0].TagName != "v2" || releases[1].TagName != "v1" {
+		t.Errorf("Expected releases from both pages, got %+v", releases)
+	}
+}
+
+func TestListReleasesRetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		fmt.Fprint(w, `[{"tag_name": "v1"}]`)
+	}))
+	defer server.Close()
+
+	releases, err := newTestClient(server).ListReleases(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("Expected the request to eventually succeed, got %v", err)
+	}
+
+	if len(releases) != 1 || requests.Load() != 3 {
+		t.Errorf("Expected 1 release after 3 requests, got %d after %d", len(releases), requests.Load())
+	}
+}
+
+func TestListReleasesGivesUpAfterMaxRetries(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	_, err := client.ListReleases(context.Background(), "owner/repo")
+
+	var githubErr *Error
+	if !errors.As(err, &githubErr) || githubErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected a GitHub error with status 500, got %v", err)
+	}
+
+	if int(requests.Load()) != client.MaxRetries+1 {
+		t.Errorf("Expected %d requests, got %d", client.MaxRetries+1, requests.Load())
+	}
+}
+
+func TestListReleasesDoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server).ListReleases(context.Background(), "owner/repo")
+
+	var githubErr *Error
+	if !errors.As(err, &githubErr) || githubErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a GitHub error with status 404, got %v", err)
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected a single request, got %d", requests.Load())
+	}
+}
+
+func TestRateLimitedResponseHonorsRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	if _, err := newTestClient(server).ListReleases(context.Background(), "owner/repo"); err != nil {
+		t.Fatalf("Expected the request to be retried, got %v", err)
+	}
+
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestRateLimitIsTrackedAndRespected(t *testing.T) {
+	var requests atomic.Int32
+	reset := time.Now().Add(time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		setRateLimitHeaders(w, 10, reset)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	if _, err := client.ListReleases(context.Background(), "owner/repo"); err != nil {
+		t.Fatalf("Expected the first request to succeed, got %v", err)
+	}
+
+	rateLimit := client.RateLimit()
+	if rateLimit.Limit != 5000 || rateLimit.Remaining != 10 || rateLimit.Reset.Unix() != reset.Unix() {
+		t.Errorf("Expected the rate limit to be tracked, got %+v", rateLimit)
+	}
+
+	if _, err := client.ListReleases(context.Background(), "owner/repo"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected background requests to back off near the limit, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/repos/owner/repo/releases/assets/1", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected direct requests to use the reserve, got %v", err)
+	}
+	resp.Body.Close()
+
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", requests.Load())
+	}
+}
@@ -3,9 +3,12 @@ module github.com/senither/dalamud-plugin-listing
 go 1.25.0
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/gofiber/fiber/v3 v3.3.0
 	github.com/gofiber/template/jet/v3 v3.0.2
 	github.com/prometheus/client_golang v1.19.1
+	go.etcd.io/bbolt v1.4.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// AdminAuth only lets requests through that send the ADMIN_TOKEN environment
+// variable as a bearer token, the admin API is disabled if it isn't set.
+func AdminAuth(c fiber.Ctx) error {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status": fiber.StatusNotFound,
+			"reason": "The admin API is disabled, set ADMIN_TOKEN to enable it.",
+			"path":   c.Path(),
+		})
+	}
+
+	provided, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(provided)), []byte(token)) != 1 {
+		c.Set("WWW-Authenticate", `Bearer realm="admin"`)
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status": fiber.StatusUnauthorized,
+			"reason": "A valid admin token is required to access this endpoint.",
+			"path":   c.Path(),
+		})
+	}
+
+	return c.Next()
+}
@@ -16,3 +16,11 @@ func ParseRepositoryParam(c fiber.Ctx) error {
 
 	return c.Next()
 }
+
+// ParseRepositoryNameParam sets the repository like ParseRepositoryParam for
+// routes that take the plugin name as a named parameter.
+func ParseRepositoryNameParam(c fiber.Ctx) error {
+	c.Locals("repository", strings.ReplaceAll(c.Params("name"), "%20", " "))
+
+	return c.Next()
+}
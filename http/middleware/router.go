@@ -19,6 +19,14 @@ func RouteSplitter(htmlRoute fiber.Handler, jsonRoute fiber.Handler) fiber.Handl
 	}
 }
 
+// CountApiRequest counts requests to the versioned API, which always responds
+// with JSON so it doesn't need the route splitter.
+func CountApiRequest(c fiber.Ctx) error {
+	metrics.IncrementRouteRequestCounter(metrics.ApiRoute)
+
+	return c.Next()
+}
+
 func isJsonRequest(c fiber.Ctx) bool {
 	return c.Get("Accept") == "application/json" ||
 		strings.HasPrefix(c.Get("User-Agent"), "Dalamud/") ||
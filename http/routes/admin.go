@@ -0,0 +1,666 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/cron"
+	"github.com/senither/dalamud-plugin-listing/cron/jobs"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type AdminSource struct {
+	Url       string           `json:"url"`
+	Name      string           `json:"name"`
+	Enabled   bool             `json:"enabled"`
+	Scheduled bool             `json:"scheduled"`
+	Interval  string           `json:"interval,omitempty"`
+	NextRunAt *time.Time       `json:"next_run_at,omitempty"`
+	Plugins   int              `json:"plugins"`
+	Status    AdminFetchStatus `json:"status"`
+	Circuit   string           `json:"circuit"`
+	OnFailure string           `json:"on_failure"`
+	Trust     string           `json:"trust"`
+	Priority  int              `json:"priority"`
+	Shadowed  int              `json:"shadowed"`
+	Blocked   int              `json:"blocked"`
+}
+
+type AdminPlugin struct {
+	Name          string           `json:"name"`
+	Provider      string           `json:"provider"`
+	Host          string           `json:"host"`
+	Private       bool             `json:"private"`
+	StableOnly    bool             `json:"stable_only"`
+	Scheduled     bool             `json:"scheduled"`
+	Interval      string           `json:"interval,omitempty"`
+	NextRunAt     *time.Time       `json:"next_run_at,omitempty"`
+	InternalName  string           `json:"internal_name,omitempty"`
+	Version       string           `json:"version,omitempty"`
+	Status        AdminFetchStatus `json:"status"`
+	RepositoryUrl string           `json:"repository_url"`
+}
+
+type AdminFetchStatus struct {
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	Failures      int        `json:"consecutive_failures"`
+	RetryAt       *time.Time `json:"retry_at,omitempty"`
+}
+
+type AdminJob struct {
+	Name        string     `json:"name"`
+	Interval    string     `json:"interval"`
+	Running     bool       `json:"running"`
+	Runs        int        `json:"runs"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastSuccess *time.Time `json:"last_success_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	NextRunAt   *time.Time `json:"next_run_at"`
+	Duration    string     `json:"duration"`
+}
+
+type AdminConflict struct {
+	InternalName  string                `json:"internal_name"`
+	ServedBy      string                `json:"served_by"`
+	ServedVersion string                `json:"served_version"`
+	Shadowed      []AdminShadowedPlugin `json:"shadowed"`
+}
+
+type AdminShadowedPlugin struct {
+	Url      string `json:"url"`
+	Author   string `json:"author"`
+	Version  string `json:"version"`
+	Priority int    `json:"priority"`
+	Reason   string `json:"reason"`
+}
+
+type AdminBlockedPlugin struct {
+	InternalName string `json:"internal_name"`
+	Name         string `json:"name"`
+	Author       string `json:"author"`
+	Version      string `json:"version"`
+	Url          string `json:"url"`
+	Reason       string `json:"reason"`
+}
+
+type AdminOverride struct {
+	InternalName string            `json:"internal_name,omitempty"`
+	Source       string            `json:"source,omitempty"`
+	Name         *string           `json:"name,omitempty"`
+	Author       *string           `json:"author,omitempty"`
+	Punchline    *string           `json:"punchline,omitempty"`
+	Description  *string           `json:"description,omitempty"`
+	IconUrl      *string           `json:"icon_url,omitempty"`
+	RepoUrl      *string           `json:"repo_url,omitempty"`
+	Tags         AdminTagOverrides `json:"tags"`
+	Matches      []string          `json:"matches"`
+}
+
+type AdminTagOverrides struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+type adminSourceRequest struct {
+	Url  string `json:"url"`
+	Name string `json:"name"`
+}
+
+type adminPluginRequest struct {
+	Name string `json:"name"`
+}
+
+type adminPurgeRequest struct {
+	InternalName string `json:"internal_name"`
+	Url          string `json:"url"`
+}
+
+type adminPinRequest struct {
+	InternalName string `json:"internal_name"`
+	Version      string `json:"version"`
+	Url          string `json:"url"`
+}
+
+func AdminListSources(c fiber.Ctx) error {
+	cfg := config.Get()
+	scheduled := jobs.GetRepositoryJobs()
+
+	urls := state.GetUrls()
+	for _, source := range cfg.Sources {
+		if !slices.Contains(urls, source.Url) {
+			urls = append(urls, source.Url)
+		}
+	}
+
+	sources := make([]AdminSource, 0, len(urls))
+	for _, url := range urls {
+		settings := cfg.Source(url)
+		status := state.GetSourceStatus(url)
+
+		source := AdminSource{
+			Url:       url,
+			Name:      settings.DisplayName(),
+			Enabled:   settings.IsEnabled(),
+			Plugins:   len(state.GetRepositoriesByOriginUrl(url)),
+			Status:    newAdminFetchStatus(status.FetchStatus),
+			Circuit:   status.Circuit(),
+			OnFailure: settings.FailurePolicy(cfg.Global),
+			Trust:     settings.TrustLevel(),
+			Priority:  settings.Priority,
+			Shadowed:  countShadowedByOriginUrl(url),
+			Blocked:   countBlockedByOriginUrl(url),
+		}
+
+		if job, ok := scheduled[url]; ok {
+			source.Scheduled = true
+			source.Interval = job.Interval.String()
+			source.NextRunAt = optionalTime(job.NextRun)
+		}
+
+		sources = append(sources, source)
+	}
+
+	return c.JSON(sources)
+}
+
+func AdminAddSource(c fiber.Ctx) error {
+	var req adminSourceRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if !state.IsValidUrl(req.Url) {
+		return adminError(c, fiber.StatusUnprocessableEntity, "The source URL is not a valid URL.")
+	}
+
+	if err := config.AddSource(req.Url, req.Name); err != nil {
+		return adminConfigError(c, err)
+	}
+
+	cron.Reload()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"url":   req.Url,
+		"added": true,
+	})
+}
+
+func AdminRemoveSource(c fiber.Ctx) error {
+	var req adminSourceRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if err := config.RemoveSource(req.Url); err != nil {
+		return adminConfigError(c, err)
+	}
+
+	cron.Reload()
+
+	return c.JSON(fiber.Map{
+		"url":     req.Url,
+		"removed": true,
+	})
+}
+
+func AdminEnableSource(c fiber.Ctx) error {
+	return setSourceEnabled(c, true)
+}
+
+func AdminDisableSource(c fiber.Ctx) error {
+	return setSourceEnabled(c, false)
+}
+
+func setSourceEnabled(c fiber.Ctx, enabled bool) error {
+	var req adminSourceRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if err := config.SetSourceEnabled(req.Url, enabled); err != nil {
+		return adminConfigError(c, err)
+	}
+
+	cron.Reload()
+
+	return c.JSON(fiber.Map{
+		"url":     req.Url,
+		"enabled": enabled,
+	})
+}
+
+func AdminRefreshSource(c fiber.Ctx) error {
+	var req adminSourceRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if !slices.Contains(state.GetUrls(), req.Url) {
+		return adminError(c, fiber.StatusNotFound, "No enabled source was found with the given URL.")
+	}
+
+	if err := jobs.RunRepositoryUpdateJob(req.Url); err != nil {
+		return adminError(c, fiber.StatusBadGateway, "Failed to refresh the source: "+err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"url":     req.Url,
+		"plugins": len(state.GetRepositoriesByOriginUrl(req.Url)),
+	})
+}
+
+func AdminListPlugins(c fiber.Ctx) error {
+	scheduled := jobs.GetPluginReleasesJobs()
+
+	internalPlugins := state.GetInternalPlugins()
+
+	plugins := make([]AdminPlugin, 0, len(internalPlugins))
+	for _, ip := range internalPlugins {
+		plugin := AdminPlugin{
+			Name:          ip.Name,
+			Provider:      ip.Provider,
+			Host:          ip.Host,
+			Private:       ip.Private,
+			StableOnly:    ip.StableOnly,
+			Status:        newAdminFetchStatus(state.GetPluginStatus(ip.Name)),
+			RepositoryUrl: ip.RepositoryUrl(),
+		}
+
+		if job, ok := scheduled[ip.Name]; ok {
+			plugin.Scheduled = true
+			plugin.Interval = job.Interval.String()
+			plugin.NextRunAt = optionalTime(job.NextRun)
+		}
+
+		if repository := state.GetRepositoryByGitHubReleaseRepositoryName(ip.Name); repository != nil {
+			plugin.InternalName = repository.InternalName
+			plugin.Version = repository.AssemblyVersion.String()
+		}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return c.JSON(plugins)
+}
+
+func AdminRefreshPlugin(c fiber.Ctx) error {
+	var req adminPluginRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if err := jobs.RunGitHubReleaseUpdateJob(req.Name); err != nil {
+		if errors.Is(err, jobs.ErrUnknownPlugin) {
+			return adminError(c, fiber.StatusNotFound, "No internal plugin was found with the given name.")
+		}
+
+		return adminError(c, fiber.StatusBadGateway, "Failed to refresh the plugin: "+err.Error())
+	}
+
+	response := fiber.Map{"name": req.Name}
+	if repository := state.GetRepositoryByGitHubReleaseRepositoryName(req.Name); repository != nil {
+		response["version"] = repository.AssemblyVersion.String()
+	}
+
+	return c.JSON(response)
+}
+
+// AdminPurgeRepository removes the matching plugins from the state, including
+// the shadowed entries from other sources, the source validators are cleared so the next fetch downloads the source in
+// full, which allows a purged plugin to come back once it's fixed upstream.
+func AdminPurgeRepository(c fiber.Ctx) error {
+	var req adminPurgeRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if req.InternalName == "" {
+		return adminError(c, fiber.StatusUnprocessableEntity, "The internal_name field is required.")
+	}
+
+	repositories := state.GetRepositories()
+	for _, entry := range state.GetShadowedRepositories() {
+		repositories = append(repositories, entry.Repository)
+	}
+
+	purged := make([]string, 0)
+	for _, repository := range repositories {
+		if !strings.EqualFold(repository.InternalName, req.InternalName) {
+			continue
+		}
+
+		origin := repository.RepositoryOrigin.RepositoryUrl
+		if req.Url != "" && origin != req.Url {
+			continue
+		}
+
+		state.DeleteRepository(repository)
+
+		if ip := state.GetInternalPluginByRepositoryUrl(origin); ip != nil {
+			state.DeleteReleaseMetadata(ip.Name)
+		} else {
+			state.ClearSourceValidators(origin)
+		}
+
+		purged = append(purged, origin)
+	}
+
+	if len(purged) == 0 {
+		return adminError(c, fiber.StatusNotFound, "No plugin was found with the given internal name.")
+	}
+
+	return c.JSON(fiber.Map{
+		"internal_name": req.InternalName,
+		"purged":        purged,
+	})
+}
+
+// AdminPinPlugin serves a previous entry from the history of a plugin instead
+// of the latest entry, new versions are still recorded while it's pinned.
+func AdminPinPlugin(c fiber.Ctx) error {
+	var req adminPinRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if req.InternalName == "" || req.Version == "" {
+		return adminError(c, fiber.StatusUnprocessableEntity, "The internal_name and version fields are required.")
+	}
+
+	entry, err := state.PinRepositoryVersion(req.InternalName, req.Version, req.Url)
+	if err != nil {
+		if errors.Is(err, state.ErrHistoryNotFound) {
+			return adminError(c, fiber.StatusNotFound, "No history was found for the plugin.")
+		}
+
+		return adminError(c, fiber.StatusNotFound, "No history entry was found with the given version.")
+	}
+
+	return c.JSON(fiber.Map{
+		"internal_name": entry.Repository.InternalName,
+		"version":       entry.Repository.AssemblyVersion.String(),
+		"url":           entry.Repository.RepositoryOrigin.RepositoryUrl,
+	})
+}
+
+func AdminUnpinPlugin(c fiber.Ctx) error {
+	var req adminPinRequest
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if !state.UnpinRepository(req.InternalName) {
+		return adminError(c, fiber.StatusNotFound, "The plugin is not pinned.")
+	}
+
+	return c.JSON(fiber.Map{"internal_name": req.InternalName})
+}
+
+// AdminListBlocked lists the plugins that are left out of the listing by the
+// policy, along with the reason of the rule that blocked them.
+func AdminListBlocked(c fiber.Ctx) error {
+	entries := state.GetBlockedRepositories()
+
+	result := make([]AdminBlockedPlugin, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, AdminBlockedPlugin{
+			InternalName: entry.Repository.InternalName,
+			Name:         entry.Repository.Name,
+			Author:       entry.Repository.Author,
+			Version:      entry.Repository.AssemblyVersion.String(),
+			Url:          entry.Repository.RepositoryOrigin.RepositoryUrl,
+			Reason:       entry.Reason,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b AdminBlockedPlugin) int {
+		return strings.Compare(strings.ToLower(a.InternalName), strings.ToLower(b.InternalName))
+	})
+
+	return c.JSON(result)
+}
+
+// AdminListOverrides lists the overrides from the config along with the
+// plugins each override currently matches.
+func AdminListOverrides(c fiber.Ctx) error {
+	repositories := state.GetRepositories()
+
+	result := make([]AdminOverride, 0, len(config.Get().Overrides))
+	for _, override := range config.Get().Overrides {
+		entry := newAdminOverride(override)
+
+		for _, repository := range repositories {
+			if override.Matches(repository) {
+				entry.Matches = append(entry.Matches, repository.InternalName)
+			}
+		}
+
+		result = append(result, entry)
+	}
+
+	return c.JSON(result)
+}
+
+// AdminSetOverride adds an override to the config, or replaces the override
+// with the same internal name and source.
+func AdminSetOverride(c fiber.Ctx) error {
+	var req AdminOverride
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if req.InternalName == "" && req.Source == "" {
+		return adminError(c, fiber.StatusUnprocessableEntity, "The internal_name or source field is required.")
+	}
+
+	if err := config.SetOverride(req.override()); err != nil {
+		return adminConfigError(c, err)
+	}
+
+	cron.Reload()
+
+	return c.JSON(fiber.Map{
+		"internal_name": req.InternalName,
+		"source":        req.Source,
+		"saved":         true,
+	})
+}
+
+func AdminRemoveOverride(c fiber.Ctx) error {
+	var req AdminOverride
+	if err := decodeAdminRequest(c, &req); err != nil {
+		return adminError(c, fiber.StatusBadRequest, "Failed to decode request: "+err.Error())
+	}
+
+	if err := config.RemoveOverride(req.InternalName, req.Source); err != nil {
+		return adminConfigError(c, err)
+	}
+
+	cron.Reload()
+
+	return c.JSON(fiber.Map{
+		"internal_name": req.InternalName,
+		"source":        req.Source,
+		"removed":       true,
+	})
+}
+
+func AdminListJobs(c fiber.Ctx) error {
+	statuses := jobs.GetJobStatuses()
+
+	result := make([]AdminJob, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, AdminJob{
+			Name:        status.Name,
+			Interval:    status.Interval.String(),
+			Running:     status.Running,
+			Runs:        status.Runs,
+			LastRunAt:   optionalTime(status.LastRun),
+			LastSuccess: optionalTime(status.LastSuccess),
+			LastError:   status.LastError,
+			NextRunAt:   optionalTime(status.NextRun),
+			Duration:    status.Duration.String(),
+		})
+	}
+
+	slices.SortFunc(result, func(a, b AdminJob) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return c.JSON(result)
+}
+
+// AdminListConflicts lists the plugins that are provided by several sources,
+// along with the source that is served and the entries it's hiding.
+func AdminListConflicts(c fiber.Ctx) error {
+	cfg := config.Get()
+
+	served := make(map[string]state.Repository)
+	for _, repository := range state.GetRepositories() {
+		served[strings.ToLower(repository.InternalName)] = repository
+	}
+
+	conflicts := make(map[string]*AdminConflict)
+	for _, entry := range state.GetShadowedRepositories() {
+		key := strings.ToLower(entry.Repository.InternalName)
+
+		conflict, ok := conflicts[key]
+		if !ok {
+			conflict = &AdminConflict{
+				InternalName:  served[key].InternalName,
+				ServedBy:      entry.ShadowedBy,
+				ServedVersion: served[key].AssemblyVersion.String(),
+			}
+
+			conflicts[key] = conflict
+		}
+
+		origin := entry.Repository.RepositoryOrigin.RepositoryUrl
+		conflict.Shadowed = append(conflict.Shadowed, AdminShadowedPlugin{
+			Url:      origin,
+			Author:   entry.Repository.Author,
+			Version:  entry.Repository.AssemblyVersion.String(),
+			Priority: cfg.Source(origin).Priority,
+			Reason:   entry.Reason,
+		})
+	}
+
+	result := make([]AdminConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		result = append(result, *conflict)
+	}
+
+	slices.SortFunc(result, func(a, b AdminConflict) int {
+		return strings.Compare(strings.ToLower(a.InternalName), strings.ToLower(b.InternalName))
+	})
+
+	return c.JSON(result)
+}
+
+func decodeAdminRequest(c fiber.Ctx, req any) error {
+	return json.NewDecoder(bytes.NewReader(c.Body())).Decode(req)
+}
+
+func adminConfigError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, config.ErrSourceExists):
+		return adminError(c, fiber.StatusConflict, "The source already exists.")
+	case errors.Is(err, config.ErrSourceNotFound):
+		return adminError(c, fiber.StatusNotFound, "No source was found with the given URL.")
+	case errors.Is(err, config.ErrOverrideNotFound):
+		return adminError(c, fiber.StatusNotFound, "No override was found with the given internal name and source.")
+	}
+
+	return adminError(c, fiber.StatusUnprocessableEntity, "Failed to update the config: "+err.Error())
+}
+
+func adminError(c fiber.Ctx, status int, reason string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status": status,
+		"reason": reason,
+		"path":   c.Path(),
+	})
+}
+
+func newAdminFetchStatus(status state.FetchStatus) AdminFetchStatus {
+	return AdminFetchStatus{
+		LastFetchedAt: optionalTime(status.LastFetchedAt),
+		LastSuccessAt: optionalTime(status.LastSuccessAt),
+		LastError:     status.LastError,
+		Failures:      status.ConsecutiveFailures,
+		RetryAt:       optionalTime(status.RetryAt),
+	}
+}
+
+func newAdminOverride(override config.Override) AdminOverride {
+	return AdminOverride{
+		InternalName: override.InternalName,
+		Source:       override.Source,
+		Name:         override.Name,
+		Author:       override.Author,
+		Punchline:    override.Punchline,
+		Description:  override.Description,
+		IconUrl:      override.IconUrl,
+		RepoUrl:      override.RepoUrl,
+		Tags: AdminTagOverrides{
+			Add:    override.Tags.Add,
+			Remove: override.Tags.Remove,
+		},
+		Matches: make([]string, 0),
+	}
+}
+
+func (o AdminOverride) override() config.Override {
+	return config.Override{
+		InternalName: o.InternalName,
+		Source:       o.Source,
+		Name:         o.Name,
+		Author:       o.Author,
+		Punchline:    o.Punchline,
+		Description:  o.Description,
+		IconUrl:      o.IconUrl,
+		RepoUrl:      o.RepoUrl,
+		Tags: config.TagOverrides{
+			Add:    o.Tags.Add,
+			Remove: o.Tags.Remove,
+		},
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func countShadowedByOriginUrl(url string) int {
+	count := 0
+	for _, entry := range state.GetShadowedRepositories() {
+		if entry.Repository.RepositoryOrigin.RepositoryUrl == url {
+			count++
+		}
+	}
+
+	return count
+}
+
+func countBlockedByOriginUrl(url string) int {
+	count := 0
+	for _, entry := range state.GetBlockedRepositories() {
+		if entry.Repository.RepositoryOrigin.RepositoryUrl == url {
+			count++
+		}
+	}
+
+	return count
+}
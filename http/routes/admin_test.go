@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestAdminPurgeRemovesShadowedEntries(t *testing.T) {
+	t.Setenv("APP_CACHE_DIR", t.TempDir())
+
+	served := state.Repository{
+		Name:            "Purged",
+		InternalName:    "Purged",
+		AssemblyVersion: "2.0.0.0",
+		RepositoryOrigin: state.RepositoryOrigin{
+			RepositoryUrl: "https://served.example.com/repo.json",
+		},
+	}
+
+	shadowed := served
+	shadowed.AssemblyVersion = "1.0.0.0"
+	shadowed.RepositoryOrigin.RepositoryUrl = "https://shadowed.example.com/repo.json"
+
+	state.UpsertRepository(served)
+	state.UpsertRepository(shadowed)
+	defer state.DeleteRepository(served)
+
+	app := fiber.New()
+	app.Post("/admin/purge", AdminPurgeRepository)
+
+	body := `{"internal_name": "Purged", "url": "https://shadowed.example.com/repo.json"}`
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/admin/purge", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the shadowed entry to be purged, got %d", resp.StatusCode)
+	}
+
+	for _, entry := range state.GetShadowedRepositories() {
+		if entry.Repository.InternalName == "Purged" {
+			t.Errorf("Expected the shadowed entry to be removed, got %+v", entry)
+		}
+	}
+}
@@ -0,0 +1,684 @@
+package routes
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// The versioned API always responds with JSON, lists are paginated using the
+// limit and an opaque cursor that holds the key of the last item on the
+// previous page, so pages don't skip or repeat items if the list changes.
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 200
+)
+
+// Error codes used in the error envelope of the versioned API.
+const (
+	ApiErrorInvalidParameter = "invalid_parameter"
+	ApiErrorNotFound         = "not_found"
+	ApiErrorInternal         = "internal_error"
+)
+
+// apiSortKeys are the sort keys accepted by the plugin list, unlike the
+// homepage the downloads keys sort in the direction they are named after.
+var apiSortKeys = []string{"name-asc", "name-desc", "downloads-asc", "downloads-desc", "recently-updated"}
+
+type ApiResponse struct {
+	Data any      `json:"data"`
+	Meta *ApiMeta `json:"meta,omitempty"`
+}
+
+type ApiMeta struct {
+	Limit      int     `json:"limit"`
+	Total      int     `json:"total"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+type ApiErrorResponse struct {
+	Error ApiError `json:"error"`
+}
+
+type ApiError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+// ApiPlugin is a plugin as served by the versioned API, the fields don't
+// follow the Dalamud manifest so they stay the same if the manifest changes.
+type ApiPlugin struct {
+	InternalName   string     `json:"internal_name"`
+	Name           string     `json:"name"`
+	Author         string     `json:"author"`
+	Punchline      *string    `json:"punchline"`
+	Description    string     `json:"description"`
+	Tags           []string   `json:"tags"`
+	Version        string     `json:"version"`
+	TestingVersion *string    `json:"testing_version"`
+	ApiLevel       int        `json:"api_level"`
+	Outdated       bool       `json:"outdated"`
+	IconUrl        *string    `json:"icon_url"`
+	RepoUrl        *string    `json:"repo_url"`
+	DownloadUrl    *string    `json:"download_url"`
+	Downloads      int64      `json:"downloads"`
+	LastUpdatedAt  *time.Time `json:"last_updated_at"`
+	Source         string     `json:"source"`
+	Internal       bool       `json:"internal"`
+	Private        bool       `json:"private"`
+}
+
+type ApiPluginDetails struct {
+	ApiPlugin
+	Changelog *string           `json:"changelog"`
+	Origins   []ApiPluginOrigin `json:"origins"`
+}
+
+type ApiPluginOrigin struct {
+	Url        string     `json:"url"`
+	Version    string     `json:"version"`
+	ApiLevel   int        `json:"api_level"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	Served     bool       `json:"served"`
+	Internal   bool       `json:"internal"`
+	Stale      bool       `json:"stale"`
+}
+
+// ApiPluginGroup is an author or tag along with the number of plugins in it.
+type ApiPluginGroup struct {
+	Name    string `json:"name"`
+	Plugins int    `json:"plugins"`
+}
+
+type ApiSource struct {
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	Kind          string     `json:"kind"`
+	Plugins       int        `json:"plugins"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     string     `json:"last_error"`
+	Circuit       string     `json:"circuit"`
+	Expiring      bool       `json:"expiring"`
+}
+
+type ApiRelease struct {
+	Version    string `json:"version"`
+	Changelog  string `json:"changelog"`
+	CreatedAt  string `json:"created_at"`
+	Prerelease bool   `json:"prerelease"`
+	Downloads  int    `json:"downloads"`
+}
+
+// apiPage is the part of a list that was requested, after is the key of the
+// last item on the previous page.
+type apiPage struct {
+	limit int
+	scope string
+	after json.RawMessage
+}
+
+// apiCursor is encoded as the cursor of the next page, the scope identifies
+// the list along with its sort and filter the cursor was issued for.
+type apiCursor struct {
+	Scope string          `json:"scope"`
+	After json.RawMessage `json:"after"`
+}
+
+// apiOrder sorts a paginated list by the key of its items, the key must be
+// unique so the next page can start after the last item of the previous one.
+type apiOrder[T any, K any] struct {
+	key     func(item T) K
+	compare func(item T, key K) int
+}
+
+// apiPluginKey holds the values plugins are sorted by in the versioned API.
+type apiPluginKey struct {
+	Name         string    `json:"name"`
+	Downloads    int64     `json:"downloads"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	InternalName string    `json:"internal_name"`
+}
+
+// ApiListPlugins lists the plugins using the same filters as the homepage,
+// like ?tag=UI&author=Senither, sorted by the sort key.
+func ApiListPlugins(c fiber.Ctx) error {
+	fields, err := parseApiFields[ApiPlugin](c.Query("fields"))
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	sortKey := c.Query("sort", apiSortKeys[0])
+	if !slices.Contains(apiSortKeys, sortKey) {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter,
+			fmt.Sprintf("unknown sort key %q, expected one of %s", sortKey, strings.Join(apiSortKeys, ", ")),
+		)
+	}
+
+	var filter Filter
+	if err := bindFilter(c, &filter); err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, "Invalid filter: "+err.Error())
+	}
+
+	filter.defaultOutdated(config.Get().Global.ApiLevel.Outdated)
+
+	page, err := parseApiPage(c, sortKey, filter)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	repositories, meta, err := paginate(filter.apply(state.GetRepositories()), page, apiOrder[state.Repository, apiPluginKey]{
+		key: newApiPluginKey,
+		compare: func(repo state.Repository, key apiPluginKey) int {
+			return compareApiPlugins(sortKey, newApiPluginKey(repo), key)
+		},
+	})
+
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	plugins := make([]any, len(repositories))
+	for i, repo := range repositories {
+		if plugins[i], err = selectFields(newApiPlugin(repo), fields); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(ApiResponse{Data: plugins, Meta: &meta})
+}
+
+// ApiGetPlugin returns a single plugin along with every source providing it.
+func ApiGetPlugin(c fiber.Ctx) error {
+	fields, err := parseApiFields[ApiPluginDetails](c.Query("fields"))
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	plugin, err := findPluginRepositoryFromContext(c)
+	if err != nil {
+		return apiError(c, fiber.StatusNotFound, ApiErrorNotFound, "No plugin was found with the given name.")
+	}
+
+	origins := make([]ApiPluginOrigin, 0)
+	for _, origin := range getPluginOrigins(plugin) {
+		origins = append(origins, newApiPluginOrigin(origin))
+	}
+
+	data, err := selectFields(ApiPluginDetails{
+		ApiPlugin: newApiPlugin(*plugin),
+		Changelog: plugin.Changelog,
+		Origins:   origins,
+	}, fields)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(ApiResponse{Data: data})
+}
+
+func ApiListAuthors(c fiber.Ctx) error {
+	return renderApiPluginGroups(c, func(repo state.Repository) []string {
+		return strings.Split(repo.Author, ",")
+	})
+}
+
+func ApiListTags(c fiber.Ctx) error {
+	return renderApiPluginGroups(c, func(repo state.Repository) []string {
+		return repo.Tags
+	})
+}
+
+// ApiListSources lists the repositories followed by the internal plugins,
+// sorted by their URL.
+func ApiListSources(c fiber.Ctx) error {
+	page, err := parseApiPage(c)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	report := buildSourceHealthReport()
+
+	sources := make([]ApiSource, 0, len(report.Sources)+len(report.Plugins))
+	for _, health := range report.Sources {
+		sources = append(sources, newApiSource(health, "repository"))
+	}
+
+	for _, health := range report.Plugins {
+		sources = append(sources, newApiSource(health, "plugin"))
+	}
+
+	sources, meta, err := paginate(sources, page, apiOrder[ApiSource, ApiSource]{
+		key: func(source ApiSource) ApiSource {
+			return ApiSource{Url: source.Url, Kind: source.Kind}
+		},
+		compare: func(source ApiSource, key ApiSource) int {
+			return cmp.Or(strings.Compare(source.Url, key.Url), strings.Compare(source.Kind, key.Kind))
+		},
+	})
+
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	return c.JSON(ApiResponse{Data: sources, Meta: &meta})
+}
+
+// ApiGetChangelog lists the releases of a plugin given as Author/InternalName,
+// or returns a single release if a version is given after the name.
+func ApiGetChangelog(c fiber.Ctx) error {
+	page, err := parseApiPage(c)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	repository, _ := c.Locals("repository").(string)
+
+	_, releases, version, lookupErr := findChangelog(repository)
+	if lookupErr != nil {
+		code := ApiErrorNotFound
+		if lookupErr.status == fiber.StatusBadRequest {
+			code = ApiErrorInvalidParameter
+		}
+
+		return apiError(c, lookupErr.status, code, lookupErr.message)
+	}
+
+	if version != "" {
+		for _, release := range releases.Releases {
+			if release.TagName == version {
+				return c.JSON(ApiResponse{Data: newApiRelease(release)})
+			}
+		}
+
+		return apiError(c, fiber.StatusNotFound, ApiErrorNotFound, "The requested release version could not be found")
+	}
+
+	changelog := make([]ApiRelease, len(releases.Releases))
+	for i, release := range releases.Releases {
+		changelog[i] = newApiRelease(release)
+	}
+
+	changelog, meta, err := paginate(changelog, page, apiOrder[ApiRelease, ApiRelease]{
+		key: func(release ApiRelease) ApiRelease {
+			return ApiRelease{Version: release.Version, CreatedAt: release.CreatedAt}
+		},
+		compare: func(release ApiRelease, key ApiRelease) int {
+			return cmp.Or(strings.Compare(key.CreatedAt, release.CreatedAt), strings.Compare(release.Version, key.Version))
+		},
+	})
+
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	return c.JSON(ApiResponse{Data: changelog, Meta: &meta})
+}
+
+// compareApiPlugins orders the plugins for the given sort key, names are
+// compared as is, like on the homepage, and ties are broken by InternalName.
+func compareApiPlugins(sortKey string, a, b apiPluginKey) int {
+	var order int
+
+	switch sortKey {
+	case "name-desc":
+		order = strings.Compare(b.Name, a.Name)
+	case "downloads-asc":
+		order = cmp.Compare(a.Downloads, b.Downloads)
+	case "downloads-desc":
+		order = cmp.Compare(b.Downloads, a.Downloads)
+	case "recently-updated":
+		order = b.UpdatedAt.Compare(a.UpdatedAt)
+
+	default:
+		order = strings.Compare(a.Name, b.Name)
+	}
+
+	return cmp.Or(order, strings.Compare(a.InternalName, b.InternalName))
+}
+
+func newApiPluginKey(repo state.Repository) apiPluginKey {
+	return apiPluginKey{
+		Name:         repo.Name,
+		Downloads:    repo.Downloads(),
+		UpdatedAt:    getLastUpdated(repo).UTC(),
+		InternalName: repo.InternalName,
+	}
+}
+
+func ApiNotFound(c fiber.Ctx) error {
+	return apiError(c, fiber.StatusNotFound, ApiErrorNotFound, "The resource you requested does not exist.")
+}
+
+func renderApiPluginGroups(c fiber.Ctx, names func(repo state.Repository) []string) error {
+	page, err := parseApiPage(c)
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	groups, meta, err := paginate(groupPlugins(state.GetRepositories(), names), page, apiOrder[ApiPluginGroup, string]{
+		key: func(group ApiPluginGroup) string {
+			return strings.ToLower(group.Name)
+		},
+		compare: func(group ApiPluginGroup, key string) int {
+			return strings.Compare(strings.ToLower(group.Name), key)
+		},
+	})
+
+	if err != nil {
+		return apiError(c, fiber.StatusBadRequest, ApiErrorInvalidParameter, err.Error())
+	}
+
+	return c.JSON(ApiResponse{Data: groups, Meta: &meta})
+}
+
+// groupPlugins counts the plugins for every name, names are compared without
+// case and the first spelling that is seen is used.
+func groupPlugins(repositories []state.Repository, names func(repo state.Repository) []string) []ApiPluginGroup {
+	index := make(map[string]int)
+	groups := make([]ApiPluginGroup, 0)
+
+	for _, repo := range repositories {
+		seen := make(map[string]bool)
+
+		for _, name := range names(repo) {
+			name = strings.Trim(strings.TrimSpace(name), "\"")
+			key := strings.ToLower(name)
+			if name == "" || seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			if i, ok := index[key]; ok {
+				groups[i].Plugins++
+				continue
+			}
+
+			index[key] = len(groups)
+			groups = append(groups, ApiPluginGroup{Name: name, Plugins: 1})
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b ApiPluginGroup) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return groups
+}
+
+// parseApiPage reads the limit and cursor of the request, the cursor must have
+// been issued for the same path and the same sort and filter values.
+func parseApiPage(c fiber.Ctx, values ...any) (apiPage, error) {
+	page := apiPage{limit: apiDefaultLimit}
+
+	scope, err := apiCursorScope(c.Path(), values)
+	if err != nil {
+		return page, err
+	}
+
+	page.scope = scope
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > apiMaxLimit {
+			return page, fmt.Errorf("limit must be a number between 1 and %d", apiMaxLimit)
+		}
+
+		page.limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		after, err := decodeApiCursor(value, scope)
+		if err != nil {
+			return page, fmt.Errorf("invalid cursor %q: %w", value, err)
+		}
+
+		page.after = after
+	}
+
+	return page, nil
+}
+
+// paginate sorts the items and returns the ones on the page, the meta holds
+// the cursor for the next page if there are more items.
+func paginate[T any, K any](items []T, page apiPage, order apiOrder[T, K]) ([]T, ApiMeta, error) {
+	meta := ApiMeta{Limit: page.limit, Total: len(items)}
+
+	slices.SortStableFunc(items, func(a, b T) int {
+		return order.compare(a, order.key(b))
+	})
+
+	start := 0
+	if page.after != nil {
+		var after K
+		if err := json.Unmarshal(page.after, &after); err != nil {
+			return nil, meta, fmt.Errorf("invalid cursor: %w", err)
+		}
+
+		start = len(items)
+		if i := slices.IndexFunc(items, func(item T) bool { return order.compare(item, after) > 0 }); i >= 0 {
+			start = i
+		}
+	}
+
+	end := min(start+page.limit, len(items))
+
+	if end < len(items) {
+		cursor, err := encodeApiCursor(page.scope, order.key(items[end-1]))
+		if err != nil {
+			return nil, meta, err
+		}
+
+		meta.NextCursor = &cursor
+	}
+
+	return slices.Clone(items[start:end]), meta, nil
+}
+
+// apiCursorScope hashes the path and the values a list is sorted and filtered
+// by, the hash is stored in the cursor so it can't be reused for another list.
+func apiCursorScope(path string, values []any) (string, error) {
+	encoded, err := json.Marshal(append([]any{path}, values...))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func encodeApiCursor(scope string, after any) (string, error) {
+	key, err := json.Marshal(after)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(apiCursor{Scope: scope, After: key})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeApiCursor(cursor string, scope string) (json.RawMessage, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var value apiCursor
+	if err := json.Unmarshal(decoded, &value); err != nil || len(value.After) == 0 {
+		return nil, fmt.Errorf("unknown cursor format")
+	}
+
+	if value.Scope != scope {
+		return nil, fmt.Errorf("the cursor was issued for a different sort or filter")
+	}
+
+	return value.After, nil
+}
+
+// parseApiFields returns the comma separated fields to include in the
+// response, every field of T is included if none are given.
+func parseApiFields[T any](value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var zero T
+
+	known, err := jsonFields(zero)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+
+		if _, ok := known[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func selectFields(item any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return item, nil
+	}
+
+	encoded, err := jsonFields(item)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		selected[field] = encoded[field]
+	}
+
+	return selected, nil
+}
+
+func jsonFields(item any) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func apiError(c fiber.Ctx, status int, code string, message string) error {
+	return c.Status(status).JSON(ApiErrorResponse{Error: ApiError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Path:    c.Path(),
+	}})
+}
+
+func isApiRequest(c fiber.Ctx) bool {
+	return strings.HasPrefix(c.Path(), "/api/")
+}
+
+func newApiPlugin(repo state.Repository) ApiPlugin {
+	plugin := ApiPlugin{
+		InternalName:  repo.InternalName,
+		Name:          repo.Name,
+		Author:        repo.Author,
+		Punchline:     repo.Punchline,
+		Description:   repo.Description,
+		Tags:          repo.Tags,
+		Version:       repo.AssemblyVersion.String(),
+		ApiLevel:      int(repo.DalamudApiLevel),
+		Outdated:      repo.IsOutdated,
+		IconUrl:       repo.IconUrl,
+		RepoUrl:       repo.RepoUrl,
+		DownloadUrl:   repo.DownloadLinkInstall,
+		Downloads:     repo.Downloads(),
+		LastUpdatedAt: optionalTime(getLastUpdated(repo)),
+		Source:        repo.RepositoryOrigin.RepositoryUrl,
+		Internal:      repo.RepositoryOrigin.IsInternalPlugin != nil && *repo.RepositoryOrigin.IsInternalPlugin,
+		Private:       repo.RepositoryOrigin.IsPrivatePlugin != nil && *repo.RepositoryOrigin.IsPrivatePlugin,
+	}
+
+	if plugin.Tags == nil {
+		plugin.Tags = make([]string, 0)
+	}
+
+	if repo.TestingAssemblyVersion != "" {
+		testingVersion := repo.TestingAssemblyVersion.String()
+		plugin.TestingVersion = &testingVersion
+	}
+
+	return plugin
+}
+
+func newApiPluginOrigin(origin state.PluginOrigin) ApiPluginOrigin {
+	result := ApiPluginOrigin{
+		Url:      origin.RepositoryUrl,
+		Version:  origin.AssemblyVersion.String(),
+		ApiLevel: int(origin.DalamudApiLevel),
+		Served:   origin.IsServed,
+		Internal: origin.IsInternalPlugin,
+		Stale:    origin.IsStale,
+	}
+
+	if origin.LastSeenAt != 0 {
+		result.LastSeenAt = optionalTime(time.Unix(origin.LastSeenAt, 0))
+	}
+
+	return result
+}
+
+func newApiSource(health SourceHealth, kind string) ApiSource {
+	return ApiSource{
+		Name:          health.Name,
+		Url:           health.Url,
+		Kind:          kind,
+		Plugins:       health.Plugins,
+		LastSuccessAt: health.LastSuccessAt,
+		LastError:     health.LastError,
+		Circuit:       health.Circuit,
+		Expiring:      health.Expiring,
+	}
+}
+
+func newApiRelease(release state.GitHubPluginRelease) ApiRelease {
+	return ApiRelease{
+		Version:    release.TagName,
+		Changelog:  release.Body,
+		CreatedAt:  release.CreatedAt,
+		Prerelease: release.Prerelease,
+		Downloads:  getReleaseDownloadCount(release),
+	}
+}
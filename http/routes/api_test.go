@@ -0,0 +1,169 @@
+package routes
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+var intOrder = apiOrder[int, int]{
+	key:     func(item int) int { return item },
+	compare: func(item int, key int) int { return item - key },
+}
+
+func TestPaginateFollowsCursorsToTheLastPage(t *testing.T) {
+	items := []int{5, 3, 1, 4, 2}
+
+	var seen []int
+
+	page := apiPage{limit: 2, scope: "numbers"}
+	for range len(items) {
+		result, meta, err := paginate(items, page, intOrder)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		seen = append(seen, result...)
+
+		if meta.Total != len(items) || meta.Limit != 2 {
+			t.Fatalf("Expected a total of %d with a limit of 2, got %+v", len(items), meta)
+		}
+
+		if meta.NextCursor == nil {
+			break
+		}
+
+		if page.after, err = decodeApiCursor(*meta.NextCursor, page.scope); err != nil {
+			t.Fatalf("Failed to decode cursor %q: %v", *meta.NextCursor, err)
+		}
+	}
+
+	if !slices.Equal(seen, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("Expected to see every item once in order, got %v", seen)
+	}
+
+	if _, err := decodeApiCursor("not-a-cursor", page.scope); err == nil {
+		t.Error("Expected an invalid cursor to fail")
+	}
+}
+
+func TestPaginateResumesAfterTheLastItemWhenTheListChanges(t *testing.T) {
+	page := apiPage{limit: 2, scope: "numbers"}
+
+	_, meta, err := paginate([]int{1, 2, 3, 4, 5}, page, intOrder)
+	if err != nil || meta.NextCursor == nil {
+		t.Fatalf("Expected a next cursor, got %+v (%v)", meta, err)
+	}
+
+	if page.after, err = decodeApiCursor(*meta.NextCursor, page.scope); err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+
+	// The last item of the first page is removed and a new item is added
+	// before it, neither should shift the next page.
+	result, _, err := paginate([]int{0, 1, 3, 4, 5}, page, intOrder)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result, []int{3, 4}) {
+		t.Errorf("Expected the next page to be [3 4], got %v", result)
+	}
+}
+
+func TestCursorIsRejectedForADifferentSortOrFilter(t *testing.T) {
+	scope, _ := apiCursorScope("/api/v1/plugins", []any{"name-asc", Filter{Tags: []string{"UI"}}})
+	other, _ := apiCursorScope("/api/v1/plugins", []any{"downloads-desc", Filter{Tags: []string{"UI"}}})
+	filtered, _ := apiCursorScope("/api/v1/plugins", []any{"name-asc", Filter{Tags: []string{"Jobs"}}})
+
+	cursor, err := encodeApiCursor(scope, newApiPluginKey(state.Repository{InternalName: "Alpha"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := decodeApiCursor(cursor, scope); err != nil {
+		t.Errorf("Expected the cursor to be accepted for its own list, got %v", err)
+	}
+
+	for _, scope := range []string{other, filtered} {
+		if _, err := decodeApiCursor(cursor, scope); err == nil {
+			t.Errorf("Expected the cursor to be rejected for scope %s", scope)
+		}
+	}
+}
+
+func TestSelectFieldsOnlyKeepsRequestedFields(t *testing.T) {
+	fields, err := parseApiFields[ApiPlugin]("internal_name, version,internal_name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	selected, err := selectFields(newApiPlugin(state.Repository{InternalName: "Alpha", AssemblyVersion: "1.2.0.0"}), fields)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	encoded, _ := json.Marshal(selected)
+	if string(encoded) != `{"internal_name":"Alpha","version":"1.2.0.0"}` {
+		t.Errorf("Expected only the selected fields, got %s", encoded)
+	}
+
+	if _, err := parseApiFields[ApiPlugin]("InternalName"); err == nil {
+		t.Error("Expected an unknown field to fail")
+	}
+}
+
+func TestGroupPluginsCountsEveryPluginOnce(t *testing.T) {
+	repositories := []state.Repository{
+		{InternalName: "Alpha", Author: "Senither, someone"},
+		{InternalName: "Beta", Author: "Someone, someone"},
+		{InternalName: "Gamma", Author: "Other"},
+	}
+
+	groups := groupPlugins(repositories, func(repo state.Repository) []string {
+		return strings.Split(repo.Author, ",")
+	})
+
+	expected := []ApiPluginGroup{{"Other", 1}, {"Senither", 1}, {"someone", 2}}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, groups)
+	}
+
+	for i := range expected {
+		if groups[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected, groups)
+			break
+		}
+	}
+}
+
+func TestApiDownloadSortKeysFollowTheirDirection(t *testing.T) {
+	downloads := func(count state.Count) *state.Count { return &count }
+
+	repositories := []state.Repository{
+		{InternalName: "Popular", DownloadCount: downloads(500)},
+		{InternalName: "Unknown", DownloadCount: downloads(5)},
+		{InternalName: "Known", DownloadCount: downloads(50)},
+	}
+
+	for sortKey, expected := range map[string][]string{
+		"downloads-asc":  {"Unknown", "Known", "Popular"},
+		"downloads-desc": {"Popular", "Known", "Unknown"},
+	} {
+		slices.SortStableFunc(repositories, func(a, b state.Repository) int {
+			return compareApiPlugins(sortKey, newApiPluginKey(a), newApiPluginKey(b))
+		})
+
+		var names []string
+		for _, repo := range repositories {
+			names = append(names, repo.InternalName)
+		}
+
+		if !slices.Equal(names, expected) {
+			t.Errorf("Expected %s to sort as %v, got %v", sortKey, expected, names)
+		}
+	}
+}
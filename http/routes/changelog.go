@@ -17,6 +17,18 @@ type GitHubReleaseChangelog struct {
 
 var HasError = fmt.Errorf("Empty error")
 
+// changelogError is a failed changelog lookup along with the status and the
+// message to respond with.
+type changelogError struct {
+	status  int
+	title   string
+	message string
+}
+
+func (e *changelogError) Error() string {
+	return e.message
+}
+
 func resolveChangelogRequest(c fiber.Ctx) (*state.InternalPlugin, *state.GitHubReleaseContext, string, error) {
 	repository, ok := c.Locals("repository").(string)
 	if !ok {
@@ -24,33 +36,40 @@ func resolveChangelogRequest(c fiber.Ctx) (*state.InternalPlugin, *state.GitHubR
 		return nil, nil, "", HasError
 	}
 
+	plugin, releases, version, err := findChangelog(repository)
+	if err != nil {
+		RenderErrorPage(c, err.status, err.title, err.message)
+		return nil, nil, "", HasError
+	}
+
+	return plugin, releases, version, nil
+}
+
+// findChangelog looks up the releases for a repository given as
+// Author/InternalName, optionally followed by a version.
+func findChangelog(repository string) (*state.InternalPlugin, *state.GitHubReleaseContext, string, *changelogError) {
 	parts := strings.Split(repository, "/")
 	if len(parts) > 3 || len(parts) < 2 {
-		RenderErrorPage(c, fiber.StatusBadRequest, "Bad request", "Bad request, invalid release file format")
-		return nil, nil, "", HasError
+		return nil, nil, "", &changelogError{fiber.StatusBadRequest, "Bad request", "Bad request, invalid release file format"}
 	}
 
 	plugin := state.GetInternalPluginByName(parts[0] + "/" + parts[1])
 	if plugin == nil {
 		repositoryPlugin := state.GetRepositoryByAuthorAndInternalName(parts[0], parts[1])
 		if repositoryPlugin == nil {
-			RenderErrorPage(c, fiber.StatusNotFound, "Plugin not found", "The requested plugin could not be found")
-			return nil, nil, "", HasError
+			return nil, nil, "", &changelogError{fiber.StatusNotFound, "Plugin not found", "The requested plugin could not be found"}
 		}
 
-		pluginName, _ := strings.CutPrefix(repositoryPlugin.RepositoryOrigin.RepositoryUrl, "https://github.com/")
-		plugin = state.GetInternalPluginByName(pluginName)
+		plugin = state.GetInternalPluginByRepositoryUrl(repositoryPlugin.RepositoryOrigin.RepositoryUrl)
 
 		if plugin == nil {
-			RenderErrorPage(c, fiber.StatusNotFound, "Plugin not found", "The requested plugin could not be found")
-			return nil, nil, "", HasError
+			return nil, nil, "", &changelogError{fiber.StatusNotFound, "Plugin not found", "The requested plugin could not be found"}
 		}
 	}
 
 	releases := state.GetReleaseMetadataByRepositoryName(plugin.Name)
 	if releases == nil {
-		RenderErrorPage(c, fiber.StatusNotFound, "Release not found", "No release metadata found for plugin")
-		return nil, nil, "", HasError
+		return nil, nil, "", &changelogError{fiber.StatusNotFound, "Release not found", "No release metadata found for plugin"}
 	}
 
 	version := ""
@@ -73,13 +92,7 @@ func ChangelogHtml(c fiber.Ctx) error {
 
 	var downloadCounter fiber.Map = make(fiber.Map)
 	for _, release := range releases.Releases {
-		downloadCounter[release.TagName] = 0
-		for _, asset := range release.Assets {
-			if strings.HasSuffix(asset.Name, ".zip") {
-				downloadCounter[release.TagName] = asset.DownloadCount
-				break
-			}
-		}
+		downloadCounter[release.TagName] = getReleaseDownloadCount(release)
 	}
 
 	return c.Render("changelog", fiber.Map{
@@ -134,3 +147,15 @@ func convertGitHubReleaseToChangelogResponse(release state.GitHubPluginRelease)
 		CreatedAt: release.CreatedAt,
 	}
 }
+
+// getReleaseDownloadCount returns the downloads of the plugin zip attached to
+// the release, or 0 if it has none.
+func getReleaseDownloadCount(release state.GitHubPluginRelease) int {
+	for _, asset := range release.Assets {
+		if strings.HasSuffix(asset.Name, ".zip") {
+			return asset.DownloadCount
+		}
+	}
+
+	return 0
+}
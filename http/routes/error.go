@@ -24,6 +24,10 @@ func OnlyAcceptsJsonError(c fiber.Ctx) error {
 }
 
 func InternalServerError(c fiber.Ctx, err error) error {
+	if isApiRequest(c) {
+		return apiError(c, fiber.StatusInternalServerError, ApiErrorInternal, err.Error())
+	}
+
 	return RenderErrorPage(c, fiber.StatusInternalServerError, "Internal Server Error", err.Error())
 }
 
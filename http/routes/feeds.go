@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type FeedSummary struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Url         string `json:"url"`
+	Plugins     int    `json:"plugins"`
+}
+
+// FeedJson returns the plugins in a curated feed from the config as a Dalamud
+// plugin repository, just like the homepage does for every plugin.
+func FeedJson(c fiber.Ctx) error {
+	name, _ := c.Locals("repository").(string)
+
+	feed, ok := config.Get().Feed(name)
+	if !ok {
+		return RenderErrorPage(c, fiber.StatusNotFound, "Feed Not Found", "No feed was found with the given name.")
+	}
+
+	filter := newFeedFilter(feed)
+
+	return c.JSON(filter.apply(state.GetRepositories()))
+}
+
+// FeedsJson lists every curated feed along with the URL that can be added to
+// Dalamud as a custom plugin repository.
+func FeedsJson(c fiber.Ctx) error {
+	repositories := state.GetRepositories()
+	appUrl := strings.TrimSuffix(strings.TrimSpace(os.Getenv("APP_URL")), "/")
+
+	feeds := make([]FeedSummary, 0)
+	for _, feed := range config.Get().Feeds {
+		filter := newFeedFilter(feed)
+
+		feeds = append(feeds, FeedSummary{
+			Name:        feed.Name,
+			Description: feed.Description,
+			Url:         fmt.Sprintf("%s/feeds/%s.json", appUrl, feed.Name),
+			Plugins:     len(filter.apply(repositories)),
+		})
+	}
+
+	return c.JSON(feeds)
+}
+
+func newFeedFilter(feed config.Feed) Filter {
+	filter := Filter{
+		Outdated: feed.Outdated,
+		Tags:     feed.Tags,
+		Authors:  feed.Authors,
+		Sources:  feed.Sources,
+		ApiLevel: feed.ApiLevel,
+		Include:  feed.Include,
+		Exclude:  feed.Exclude,
+	}
+
+	filter.defaultOutdated(config.Get().Global.ApiLevel.Outdated)
+
+	return filter
+}
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestFeedFilterAppliesIncludeAndExcludeLists(t *testing.T) {
+	plugin := func(internalName string, author string, tags ...string) state.Repository {
+		return state.Repository{InternalName: internalName, Name: internalName, Author: author, Tags: tags}
+	}
+
+	repositories := []state.Repository{
+		plugin("Alpha", "Senither", "UI"),
+		plugin("Beta", "Someone", "UI"),
+		plugin("Gamma", "Someone", "Combat"),
+		plugin("Delta", "Senither", "Combat"),
+	}
+
+	names := func(repos []state.Repository) []string {
+		result := make([]string, len(repos))
+		for i, repo := range repos {
+			result[i] = repo.InternalName
+		}
+
+		return result
+	}
+
+	cases := []struct {
+		name     string
+		feed     config.Feed
+		expected []string
+	}{
+		{"tags", config.Feed{Tags: []string{"ui"}}, []string{"Alpha", "Beta"}},
+		{"tags and authors", config.Feed{Tags: []string{"ui"}, Authors: []string{"senither"}}, []string{"Alpha"}},
+		{"include", config.Feed{Tags: []string{"ui"}, Include: []string{"gamma"}}, []string{"Alpha", "Beta", "Gamma"}},
+		{"only include", config.Feed{Include: []string{"delta"}}, []string{"Delta"}},
+		{"exclude", config.Feed{Exclude: []string{"beta"}}, []string{"Alpha", "Gamma", "Delta"}},
+	}
+
+	for _, c := range cases {
+		filter := newFeedFilter(c.feed)
+
+		result := names(filter.apply(repositories))
+		if len(result) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, result)
+			continue
+		}
+
+		for i := range result {
+			if result[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, result)
+				break
+			}
+		}
+	}
+}
@@ -3,11 +3,10 @@ package routes
 import (
 	"io"
 	"log/slog"
-	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/forge"
 	"github.com/senither/dalamud-plugin-listing/state"
 )
 
@@ -17,12 +16,16 @@ func DownloadPlugin(c fiber.Ctx) error {
 		return RenderErrorPage(c, fiber.StatusBadRequest, "Bad request", "Bad request, invalid repository name")
 	}
 
+	// The last two segments are the tag and asset name, everything before
+	// them is the repository name, which can be nested on GitLab.
 	parts := strings.Split(release, "/")
-	if len(parts) != 4 {
+	if len(parts) < 4 {
 		return RenderErrorPage(c, fiber.StatusBadRequest, "Bad request", "Bad request, invalid release file format")
 	}
 
-	plugin := state.GetInternalPluginByName(parts[0] + "/" + parts[1])
+	tag, assetName := parts[len(parts)-2], parts[len(parts)-1]
+
+	plugin := state.GetInternalPluginByName(strings.Join(parts[:len(parts)-2], "/"))
 	if plugin == nil || !plugin.Private {
 		return RenderErrorPage(c, fiber.StatusNotFound, "Plugin Not Found", "The requested plugin could not be found.")
 	}
@@ -34,12 +37,12 @@ func DownloadPlugin(c fiber.Ctx) error {
 
 	var assetUrl *string = nil
 	for _, rel := range releases.Releases {
-		if rel.TagName != parts[2] {
+		if rel.TagName != tag {
 			continue
 		}
 
 		for _, asset := range rel.Assets {
-			if asset.Name == parts[3] {
+			if asset.Name == assetName {
 				assetUrl = &asset.Url
 				break
 			}
@@ -50,45 +53,25 @@ func DownloadPlugin(c fiber.Ctx) error {
 		return RenderErrorPage(c, fiber.StatusNotFound, "Release Asset Not Found", "The requested release asset could not be found.")
 	}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return RenderErrorPage(c, fiber.StatusInternalServerError, "Internal Error", "Server misconfigured, missing GITHUB token environment")
-	}
-
-	req, err := http.NewRequestWithContext(c, http.MethodGet, *assetUrl, nil)
-	if err != nil {
-		return RenderErrorPage(c, fiber.StatusInternalServerError, "Internal Error", "Failed to create upstream request")
+	provider, err := forge.For(*plugin)
+	if err != nil || !provider.HasToken(*plugin) {
+		return RenderErrorPage(c, fiber.StatusInternalServerError, "Internal Error", "Server misconfigured, missing provider token environment")
 	}
 
-	req.Header.Set("Accept", "application/octet-stream")
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("User-Agent", "Dalamud Plugin Listing (https://dalamud-plugins.senither.com/)")
-	req.Header.Set("X-Forwarded-For", c.IP())
-
 	slog.Info("Requesting file download for",
 		"plugin", plugin.Name,
-		"tag", parts[2],
-		"asset", parts[3],
+		"provider", plugin.Provider,
+		"tag", tag,
+		"asset", assetName,
 		"remote", c.IP(),
 	)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := provider.DownloadAsset(c, *plugin, *assetUrl)
 	if err != nil {
-		return RenderErrorPage(c, fiber.StatusBadGateway, "Bad Gateway", "Failed to download release asset from GitHub")
+		return RenderErrorPage(c, fiber.StatusBadGateway, "Bad Gateway", "Failed to download release asset: "+err.Error())
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
-
-		return RenderErrorPage(
-			c,
-			fiber.StatusBadGateway,
-			"Bad Gateway", "Failed to download release asset from GitHub: "+strings.TrimSpace(string(body)),
-		)
-	}
-
 	for _, h := range []string{
 		"Content-Type",
 		"Content-Length",
@@ -2,9 +2,16 @@ package routes
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -13,8 +20,10 @@ import (
 )
 
 type GitHubWebhookRequest struct {
-	HookId     int64                   `json:"hook_id"`
-	Repository GitHubWebhookRepository `json:"repository"`
+	Action     string                    `json:"action"`
+	HookId     int64                     `json:"hook_id"`
+	Release    state.GitHubPluginRelease `json:"release"`
+	Repository GitHubWebhookRepository   `json:"repository"`
 }
 
 type GitHubWebhookRepository struct {
@@ -22,11 +31,68 @@ type GitHubWebhookRepository struct {
 	FullName string `json:"full_name"`
 }
 
+// deliveryRetention is how long delivery IDs are remembered, GitHub only lets
+// deliveries be redelivered manually so a day is plenty.
+const deliveryRetention = 24 * time.Hour
+
+var (
+	handledReleaseActions = []string{state.ReleasePublished, state.ReleaseEdited, state.ReleaseDeleted}
+
+	deliveriesMu sync.Mutex
+	deliveries   = make(map[string]time.Time)
+)
+
 func GitHubReleaseWebhook(c fiber.Ctx) error {
 	slog.InfoContext(c, "Handling GitHub release webhook",
 		"remote", c.IP(),
+		"event", c.Get("X-GitHub-Event"),
+		"delivery", c.Get("X-GitHub-Delivery"),
 	)
 
+	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if secret == "" {
+		slog.Warn("Rejected GitHub webhook, GITHUB_WEBHOOK_SECRET is not set")
+
+		return c.Status(http.StatusForbidden).SendString("Webhooks are not configured")
+	}
+
+	if !verifyGitHubSignature(secret, c.Body(), c.Get("X-Hub-Signature-256")) {
+		slog.Warn("Rejected GitHub webhook with an invalid signature",
+			"remote", c.IP(),
+		)
+
+		return c.Status(http.StatusUnauthorized).SendString("Invalid signature")
+	}
+
+	delivery := c.Get("X-GitHub-Delivery")
+	if delivery != "" && !reserveDelivery(delivery) {
+		slog.Info("Ignoring GitHub webhook delivery that was already handled",
+			"delivery", delivery,
+		)
+
+		return c.Status(http.StatusOK).SendString("Delivery already handled")
+	}
+
+	err := handleGitHubWebhookEvent(c)
+
+	// Only handled deliveries are remembered, so GitHub can redeliver the
+	// ones that failed.
+	if delivery != "" && (err != nil || c.Response().StatusCode() >= http.StatusMultipleChoices) {
+		releaseDelivery(delivery)
+	}
+
+	return err
+}
+
+func handleGitHubWebhookEvent(c fiber.Ctx) error {
+	switch c.Get("X-GitHub-Event") {
+	case "ping":
+		return c.Status(http.StatusOK).SendString("pong")
+	case "release":
+	default:
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
 	var req GitHubWebhookRequest = GitHubWebhookRequest{}
 	if err := json.NewDecoder(bytes.NewReader(c.Body())).Decode(&req); err != nil {
 		slog.Error("Failed to decode GitHub release webhook request",
@@ -36,23 +102,71 @@ func GitHubReleaseWebhook(c fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).SendString("Failed to decode request")
 	}
 
+	if !slices.Contains(handledReleaseActions, req.Action) {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
 	for _, internalPlugin := range state.GetInternalPlugins() {
-		if internalPlugin.Name == req.Repository.FullName {
-			go func() {
-				slog.Info("Running GitHub release update job in 10 seconds",
-					"repository", req.Repository.FullName,
-				)
+		if internalPlugin.Provider != state.ProviderGitHub || !strings.EqualFold(internalPlugin.Name, req.Repository.FullName) {
+			continue
+		}
+
+		if err := jobs.QueueGitHubReleaseEvent(internalPlugin.Name, req.Action, req.Release); err != nil {
+			return c.Status(http.StatusServiceUnavailable).SendString("Server is shutting down")
+		}
+
+		return c.SendStatus(fiber.StatusAccepted)
+	}
+
+	return c.SendStatus(fiber.StatusNotFound)
+}
+
+// verifyGitHubSignature checks the "sha256=<hex>" signature GitHub sends in
+// the X-Hub-Signature-256 header against an HMAC of the body.
+func verifyGitHubSignature(secret string, body []byte, signature string) bool {
+	encoded, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
 
-				// Run the job in 10 seconds to give GitHub time to process the release
-				// and make it available for the job to fetch.
-				<-time.After(10 * time.Second)
+	expected, err := hex.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
 
-				jobs.RunGitHubReleaseUpdateJob(req.Repository.FullName)
-			}()
+// reserveDelivery records the delivery ID before it's handled and returns
+// false if it was already handled recently or is being handled right now,
+// expired IDs are dropped.
+func reserveDelivery(delivery string) bool {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
 
-			return c.SendStatus(fiber.StatusAccepted)
+	now := time.Now()
+	for id, seenAt := range deliveries {
+		if now.Sub(seenAt) > deliveryRetention {
+			delete(deliveries, id)
 		}
 	}
 
-	return c.SendStatus(fiber.StatusNotFound)
+	if _, ok := deliveries[delivery]; ok {
+		return false
+	}
+
+	deliveries[delivery] = now
+
+	return true
+}
+
+// releaseDelivery forgets a reserved delivery ID that couldn't be handled.
+func releaseDelivery(delivery string) {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+
+	delete(deliveries, delivery)
 }
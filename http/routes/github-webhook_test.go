@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const testWebhookSecret = "test-secret"
+
+func sendWebhook(t *testing.T, event string, delivery string, body string, signature string) *http.Response {
+	t.Helper()
+
+	app := fiber.New()
+	app.Post("/webhook/github-release", GitHubReleaseWebhook)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github-release", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set("X-Hub-Signature-256", signature)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookRequiresSecret(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "")
+
+	resp := sendWebhook(t, "ping", "no-secret", `{}`, sign(`{}`))
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without a configured secret, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookRejectsInvalidSignatures(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+
+	for _, signature := range []string{"", "sha256=zz", sign(`{"other": true}`), strings.Replace(sign(`{}`), "sha256=", "sha1=", 1)} {
+		resp := sendWebhook(t, "ping", "invalid-"+signature, `{}`, signature)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for signature %q, got %d", signature, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebhookAnswersPing(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+
+	resp := sendWebhook(t, "ping", "ping-delivery", `{"zen": "Keep it simple."}`, sign(`{"zen": "Keep it simple."}`))
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a ping, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookIgnoresUnhandledEvents(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+
+	push := `{"ref": "refs/heads/main"}`
+	if resp := sendWebhook(t, "push", "push-delivery", push, sign(push)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for a push event, got %d", resp.StatusCode)
+	}
+
+	created := `{"action": "created", "repository": {"full_name": "Senither/Unknown"}}`
+	if resp := sendWebhook(t, "release", "created-delivery", created, sign(created)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for a created release action, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookDeduplicatesDeliveries(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+
+	body := `{"action": "created", "repository": {"full_name": "Senither/Unknown"}}`
+
+	if resp := sendWebhook(t, "release", "duplicate-delivery", body, sign(body)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for a created release action, got %d", resp.StatusCode)
+	}
+
+	if resp := sendWebhook(t, "release", "duplicate-delivery", body, sign(body)); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a repeated delivery, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookAcceptsRedeliveryOfFailedDeliveries(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", testWebhookSecret)
+
+	body := `{"action": "published", "repository": {"full_name": "Senither/Unknown"}}`
+
+	for range 2 {
+		if resp := sendWebhook(t, "release", "failed-delivery", body, sign(body)); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown plugin, got %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestConcurrentDeliveriesAreOnlyReservedOnce(t *testing.T) {
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+
+	for range 8 {
+		wg.Go(func() {
+			if reserveDelivery("concurrent-delivery") {
+				reserved.Add(1)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if reserved.Load() != 1 {
+		t.Errorf("Expected the delivery to be reserved once, got %d", reserved.Load())
+	}
+
+	releaseDelivery("concurrent-delivery")
+
+	if !reserveDelivery("concurrent-delivery") {
+		t.Error("Expected a released delivery to be reserved again")
+	}
+}
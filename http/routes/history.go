@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type PluginHistoryResponse struct {
+	InternalName  string               `json:"internal_name"`
+	PinnedVersion string               `json:"pinned_version,omitempty"`
+	Entries       []PluginHistoryEntry `json:"entries"`
+}
+
+type PluginHistoryEntry struct {
+	Version             string         `json:"version"`
+	TestingVersion      string         `json:"testing_version,omitempty"`
+	DalamudApiLevel     state.ApiLevel `json:"dalamud_api_level"`
+	DownloadLinkInstall *string        `json:"download_link_install,omitempty"`
+	DownloadLinkTesting *string        `json:"download_link_testing,omitempty"`
+	DownloadLinkUpdate  *string        `json:"download_link_update,omitempty"`
+	RepositoryUrl       string         `json:"repository_url"`
+	FirstSeenAt         time.Time      `json:"first_seen_at"`
+	LastSeenAt          time.Time      `json:"last_seen_at"`
+	Served              bool           `json:"served"`
+	Pinned              bool           `json:"pinned"`
+}
+
+func PluginHistoryHtml(c fiber.Ctx) error {
+	plugin, err := findPluginRepositoryFromContext(c)
+	if err != nil {
+		return RenderErrorPage(c, 404, "Plugin Not Found", "No plugin was found with the given name.")
+	}
+
+	history := buildPluginHistory(plugin)
+
+	return c.Render("plugin-history", fiber.Map{
+		"Plugin":     plugin,
+		"History":    history,
+		"HasEntries": len(history.Entries) > 0,
+	}, "layouts/app")
+}
+
+func PluginHistoryJson(c fiber.Ctx) error {
+	plugin, err := findPluginRepositoryFromContext(c)
+	if err != nil {
+		return RenderErrorPage(c, 404, "Plugin Not Found", "No plugin was found with the given name.")
+	}
+
+	return c.JSON(buildPluginHistory(plugin))
+}
+
+// buildPluginHistory lists the recorded entries for the plugin, the served
+// entry is the pinned entry if there is one, otherwise it's the entry that
+// matches the version and source of the plugin.
+func buildPluginHistory(plugin *state.Repository) PluginHistoryResponse {
+	response := PluginHistoryResponse{
+		InternalName: plugin.InternalName,
+		Entries:      make([]PluginHistoryEntry, 0),
+	}
+
+	history := state.GetPluginHistory(plugin.InternalName)
+	if history == nil {
+		return response
+	}
+
+	served := plugin.AssemblyVersion.String()
+	servedUrl := plugin.RepositoryOrigin.RepositoryUrl
+
+	if history.Pinned != nil {
+		served = history.Pinned.Repository.AssemblyVersion.String()
+		servedUrl = history.Pinned.Repository.RepositoryOrigin.RepositoryUrl
+		response.PinnedVersion = served
+	}
+
+	for _, entry := range history.Entries {
+		repo := entry.Repository
+		isServed := repo.AssemblyVersion.String() == served && repo.RepositoryOrigin.RepositoryUrl == servedUrl
+
+		response.Entries = append(response.Entries, PluginHistoryEntry{
+			Version:             repo.AssemblyVersion.String(),
+			TestingVersion:      repo.TestingAssemblyVersion.String(),
+			DalamudApiLevel:     repo.DalamudApiLevel,
+			DownloadLinkInstall: repo.DownloadLinkInstall,
+			DownloadLinkTesting: repo.DownloadLinkTesting,
+			DownloadLinkUpdate:  repo.DownloadLinkUpdate,
+			RepositoryUrl:       repo.RepositoryOrigin.RepositoryUrl,
+			FirstSeenAt:         time.Unix(entry.FirstSeenAt, 0),
+			LastSeenAt:          time.Unix(entry.LastSeenAt, 0),
+			Served:              isServed,
+			Pinned:              history.Pinned != nil && isServed,
+		})
+	}
+
+	return response
+}
@@ -1,17 +1,29 @@
 package routes
 
 import (
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/config"
 	"github.com/senither/dalamud-plugin-listing/state"
 )
 
+// Filter narrows down the plugins to the ones matching every filter that is
+// set, the included plugins are always kept and the excluded plugins are
+// always left out.
 type Filter struct {
-	Search  string   `query:"search"`
-	Tags    []string `query:"tag"`
-	Authors []string `query:"author"`
+	Search   string   `query:"search"`
+	Tags     []string `query:"tag"`
+	Authors  []string `query:"author"`
+	Sources  []string `query:"source"`
+	ApiLevel int      `query:"api"`
+	Outdated string   `query:"outdated"`
+	Include  []string `query:"include"`
+	Exclude  []string `query:"exclude"`
 }
 
 func HomepageHtml(c fiber.Ctx) error {
@@ -32,8 +44,25 @@ func HomepageHtml(c fiber.Ctx) error {
 	}, "layouts/app")
 }
 
+// HomepageJson returns every plugin as a Dalamud plugin repository, the
+// plugins can be narrowed down using the same query parameters as the
+// homepage, like ?tag=UI&author=Senither.
 func HomepageJson(c fiber.Ctx) error {
-	return c.JSON(state.GetRepositories())
+	var filter Filter
+	if err := bindFilter(c, &filter); err != nil {
+		return RenderErrorPage(c, fiber.StatusBadRequest, "Bad request", "Invalid filter: "+err.Error())
+	}
+
+	return c.JSON(ListPlugins(filter))
+}
+
+// ListPlugins returns the plugins served as a Dalamud plugin repository for
+// the filter, the outdated setting from the config is used unless the filter
+// asks for an outdated mode or an API level.
+func ListPlugins(filter Filter) []state.Repository {
+	filter.defaultOutdated(config.Get().Global.ApiLevel.Outdated)
+
+	return filter.apply(state.GetRepositories())
 }
 
 func RenderPluginListComponent(c fiber.Ctx) error {
@@ -58,18 +87,69 @@ func buildRepositories(c fiber.Ctx) ([]state.Repository, fiber.Map, error) {
 	}
 
 	var filter Filter
-	if err := c.Bind().Query(&filter); err != nil {
+	if err := bindFilter(c, &filter); err != nil {
 		return nil, nil, err
 	}
 
-	repositories = filter.bySearch(repositories)
-	repositories = filter.byTags(repositories)
-	repositories = filter.byAuthors(repositories)
+	repositories = filter.apply(repositories)
 	sortRepositories(repositories, c.Query("sort"))
 
 	return repositories, privatePlugins, nil
 }
 
+// apply returns the repositories matching the filter, if only plugins to
+// include are given the result contains just those plugins.
+func (f *Filter) apply(repositories []state.Repository) []state.Repository {
+	filtered := make([]state.Repository, 0)
+
+	if f.hasCriteria() || len(f.Include) == 0 {
+		matched := f.bySearch(repositories)
+		matched = f.byTags(matched)
+		matched = f.byAuthors(matched)
+		matched = f.bySources(matched)
+		matched = f.byApiLevel(matched)
+
+		filtered = append(filtered, matched...)
+	}
+
+	for _, repo := range repositories {
+		if containsFold(f.Include, repo.InternalName) && !slices.ContainsFunc(filtered, func(existing state.Repository) bool {
+			return existing.InternalName == repo.InternalName
+		}) {
+			filtered = append(filtered, repo)
+		}
+	}
+
+	return slices.DeleteFunc(filtered, func(repo state.Repository) bool {
+		return containsFold(f.Exclude, repo.InternalName)
+	})
+}
+
+func (f *Filter) hasCriteria() bool {
+	return f.Search != "" || len(f.Tags) > 0 || len(f.Authors) > 0 || len(f.Sources) > 0 ||
+		f.ApiLevel != 0 || (f.Outdated != "" && f.Outdated != config.OutdatedShow)
+}
+
+// defaultOutdated uses the given outdated mode unless a mode or an API level
+// was requested.
+func (f *Filter) defaultOutdated(mode string) {
+	if f.Outdated == "" && f.ApiLevel == 0 {
+		f.Outdated = mode
+	}
+}
+
+func bindFilter(c fiber.Ctx, filter *Filter) error {
+	if err := c.Bind().Query(filter); err != nil {
+		return err
+	}
+
+	if filter.Outdated != "" && !config.IsOutdatedMode(filter.Outdated) {
+		return fmt.Errorf("unknown outdated mode %q, expected show, hide or only", filter.Outdated)
+	}
+
+	return nil
+}
+
 func (f *Filter) bySearch(repositories []state.Repository) []state.Repository {
 	if f.Search == "" {
 		return repositories
@@ -113,6 +193,44 @@ func (f *Filter) byAuthors(repositories []state.Repository) []state.Repository {
 	})
 }
 
+func (f *Filter) bySources(repositories []state.Repository) []state.Repository {
+	return filterRepositories(repositories, f.Sources, func(repo state.Repository, value string) bool {
+		return strings.ToLower(repo.RepositoryOrigin.RepositoryUrl) == value
+	})
+}
+
+// byApiLevel hides or only keeps the plugins that are outdated, plugins are
+// compared to the requested API level if one is given, and otherwise to the
+// latest API level. Requesting an API level hides the other plugins unless
+// another outdated mode is given.
+func (f *Filter) byApiLevel(repositories []state.Repository) []state.Repository {
+	mode := f.Outdated
+	if mode == "" && f.ApiLevel != 0 {
+		mode = config.OutdatedHide
+	}
+
+	if mode == "" || mode == config.OutdatedShow {
+		return repositories
+	}
+
+	var filtered []state.Repository
+	for _, repo := range repositories {
+		if f.isOutdated(repo) == (mode == config.OutdatedOnly) {
+			filtered = append(filtered, repo)
+		}
+	}
+
+	return filtered
+}
+
+func (f *Filter) isOutdated(repo state.Repository) bool {
+	if f.ApiLevel == 0 {
+		return repo.IsOutdated
+	}
+
+	return repo.DalamudApiLevel != 0 && int(repo.DalamudApiLevel) != f.ApiLevel
+}
+
 func filterRepositories(
 	repositories []state.Repository,
 	filters []string,
@@ -149,11 +267,11 @@ func sortRepositories(repositories []state.Repository, sortKey string) {
 		case "name-desc":
 			return left.Name > right.Name
 		case "downloads-asc":
-			return getValue(left.DownloadCount) > getValue(right.DownloadCount)
+			return left.Downloads() > right.Downloads()
 		case "downloads-desc":
-			return getValue(left.DownloadCount) < getValue(right.DownloadCount)
+			return left.Downloads() < right.Downloads()
 		case "recently-updated":
-			return getLastUpdated(left) > getLastUpdated(right)
+			return getLastUpdated(left).After(getLastUpdated(right))
 
 		default:
 			return left.Name < right.Name
@@ -161,31 +279,16 @@ func sortRepositories(repositories []state.Repository, sortKey string) {
 	})
 }
 
-func getValue(value any) int64 {
-	switch v := value.(type) {
-	case int:
-		return int64(v)
-	case int64:
-		return v
-	case float64:
-		return int64(v)
+func getLastUpdated(repository state.Repository) time.Time {
+	if !repository.LastUpdated.IsZero() {
+		return repository.LastUpdated.Time
 	}
 
-	return 0
+	return repository.LastUpdate.Time
 }
 
-func getLastUpdated(repository state.Repository) int64 {
-	var value = int64(0)
-
-	if repository.LastUpdated != nil {
-		value = getValue(repository.LastUpdated)
-	} else if repository.LastUpdate != nil {
-		value = getValue(repository.LastUpdate)
-	}
-
-	if value < 1_000_000_000_000 {
-		value *= 1000
-	}
-
-	return value
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(strings.TrimSpace(v), value)
+	})
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/senither/dalamud-plugin-listing/state"
@@ -10,6 +11,21 @@ import (
 
 type RepositorySearchCallback func(repo *state.Repository, searchQuery string) bool
 
+// PluginWithOrigins is a plugin entry along with every source that provides
+// it, the extra field is ignored by Dalamud so the response can still be used
+// as a plugin repository.
+type PluginWithOrigins struct {
+	state.Repository
+	Origins []state.PluginOrigin `json:"Origins"`
+}
+
+// pluginOriginView is an origin with the last seen timestamp converted so it
+// can be formatted by the plugin page.
+type pluginOriginView struct {
+	state.PluginOrigin
+	LastSeen time.Time
+}
+
 func PluginHtml(c fiber.Ctx) error {
 	plugin, err := findPluginRepositoryFromContext(c)
 	if err != nil {
@@ -21,8 +37,22 @@ func PluginHtml(c fiber.Ctx) error {
 		authors = append(authors, strings.TrimSpace(author))
 	}
 
+	origins := make([]pluginOriginView, 0)
+	for _, origin := range getPluginOrigins(plugin) {
+		origins = append(origins, pluginOriginView{
+			PluginOrigin: origin,
+			LastSeen:     time.Unix(origin.LastSeenAt, 0),
+		})
+	}
+
+	history := state.GetPluginHistory(plugin.InternalName)
+
 	return c.Render("plugin", fiber.Map{
 		"Plugin":       plugin,
+		"HasHistory":   history != nil && len(history.Entries) > 0,
+		"IsPinned":     history != nil && history.Pinned != nil,
+		"Origins":      origins,
+		"HasMirrors":   len(origins) > 1,
 		"Authors":      authors,
 		"IsInternal":   plugin.RepositoryOrigin.IsInternalPlugin != nil && *plugin.RepositoryOrigin.IsInternalPlugin,
 		"IsPrivate":    plugin.RepositoryOrigin.IsPrivatePlugin != nil && *plugin.RepositoryOrigin.IsPrivatePlugin,
@@ -37,7 +67,19 @@ func PluginJson(c fiber.Ctx) error {
 		return RenderErrorPage(c, 404, "Plugin Not Found", "No plugin was found with the given name.")
 	}
 
-	return c.JSON([]state.Repository{*plugin})
+	return c.JSON([]PluginWithOrigins{{
+		Repository: *plugin,
+		Origins:    getPluginOrigins(plugin),
+	}})
+}
+
+func getPluginOrigins(plugin *state.Repository) []state.PluginOrigin {
+	origins := state.GetRepositoryOrigins(plugin.InternalName)
+	if origins == nil {
+		return make([]state.PluginOrigin, 0)
+	}
+
+	return origins
 }
 
 func SearchPluginsByName(c fiber.Ctx) error {
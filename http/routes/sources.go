@@ -0,0 +1,152 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/senither/dalamud-plugin-listing/config"
+	"github.com/senither/dalamud-plugin-listing/cron/jobs"
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+type SourceHealth struct {
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	Plugins       int        `json:"plugins"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	HttpStatus    int        `json:"http_status,omitempty"`
+	ResponseSize  int64      `json:"response_size"`
+	Warnings      []string   `json:"warnings"`
+	Circuit       string     `json:"circuit,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	Expiring      bool       `json:"expiring"`
+	Rejected      int        `json:"rejected"`
+	Shadowed      int        `json:"shadowed"`
+	Overridden    []string   `json:"overridden"`
+
+	Validation []PluginValidation `json:"validation,omitempty"`
+}
+
+type PluginValidation struct {
+	Index        int               `json:"index"`
+	InternalName string            `json:"internal_name"`
+	Rejected     bool              `json:"rejected"`
+	Issues       []ValidationIssue `json:"issues"`
+}
+
+type ValidationIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type SourceHealthReport struct {
+	Sources []SourceHealth `json:"sources"`
+	Plugins []SourceHealth `json:"plugins"`
+}
+
+func SourcesHtml(c fiber.Ctx) error {
+	return c.Render("sources", fiber.Map{
+		"Report":      buildSourceHealthReport(),
+		"ExpireAfter": config.Get().Global.ExpireAfter.Duration().String(),
+	}, "layouts/app")
+}
+
+func SourcesJson(c fiber.Ctx) error {
+	return c.JSON(buildSourceHealthReport())
+}
+
+func buildSourceHealthReport() SourceHealthReport {
+	cfg := config.Get()
+
+	urls := state.GetUrls()
+	report := SourceHealthReport{
+		Sources: make([]SourceHealth, 0, len(urls)),
+		Plugins: make([]SourceHealth, 0, state.GetInternalPluginSize()),
+	}
+
+	for _, url := range urls {
+		status := state.GetSourceStatus(url)
+
+		health := newSourceHealth(cfg.Source(url).DisplayName(), url, status.FetchStatus)
+		health.Plugins = len(state.GetRepositoriesByOriginUrl(url))
+		health.Overridden = getOverriddenPlugins(cfg, url)
+		health.Circuit = status.Circuit()
+		health.Shadowed = countShadowedByOriginUrl(url)
+
+		for _, result := range status.Validation {
+			if result.Rejected {
+				health.Rejected++
+			}
+
+			health.Validation = append(health.Validation, newPluginValidation(result))
+		}
+
+		report.Sources = append(report.Sources, health)
+	}
+
+	for _, ip := range state.GetInternalPlugins() {
+		health := newSourceHealth(ip.Name, ip.RepositoryUrl(), state.GetPluginStatus(ip.Name))
+		health.Plugins = len(state.GetRepositoriesByOriginUrl(ip.RepositoryUrl()))
+		health.Overridden = getOverriddenPlugins(cfg, ip.RepositoryUrl())
+
+		report.Plugins = append(report.Plugins, health)
+	}
+
+	return report
+}
+
+// getOverriddenPlugins returns the InternalName of every plugin from the source
+// that is patched by an override.
+func getOverriddenPlugins(cfg *config.Config, url string) []string {
+	overridden := make([]string, 0)
+	for _, repository := range state.GetRepositoriesByOriginUrl(url) {
+		if cfg.HasOverride(repository) {
+			overridden = append(overridden, repository.InternalName)
+		}
+	}
+
+	return overridden
+}
+
+func newSourceHealth(name string, url string, status state.FetchStatus) SourceHealth {
+	expiresAt, expiring := jobs.GetExpiry(url)
+
+	warnings := status.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	return SourceHealth{
+		Name:          name,
+		Url:           url,
+		LastFetchedAt: optionalTime(status.LastFetchedAt),
+		LastSuccessAt: optionalTime(status.LastSuccessAt),
+		LastError:     status.LastError,
+		HttpStatus:    status.HttpStatus,
+		ResponseSize:  status.ResponseSize,
+		Warnings:      warnings,
+		ExpiresAt:     optionalTime(expiresAt),
+		Expiring:      expiring,
+	}
+}
+
+func newPluginValidation(result state.ValidationResult) PluginValidation {
+	issues := make([]ValidationIssue, len(result.Issues))
+	for i, issue := range result.Issues {
+		issues[i] = ValidationIssue{
+			Rule:     issue.Rule,
+			Severity: issue.Severity,
+			Message:  issue.Message,
+		}
+	}
+
+	return PluginValidation{
+		Index:        result.Index,
+		InternalName: result.InternalName,
+		Rejected:     result.Rejected,
+		Issues:       issues,
+	}
+}
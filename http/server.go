@@ -35,17 +35,55 @@ func SetupServer() {
 	app.Post("/webhook/github-release", routes.GitHubReleaseWebhook)
 
 	app.Get("/download/*", middleware.ParseRepositoryParam, routes.DownloadPlugin)
+	app.Get("/plugin/:name/history.json", middleware.ParseRepositoryNameParam, routes.PluginHistoryJson)
+	app.Get("/plugin/:name/history", middleware.ParseRepositoryNameParam, middleware.RouteSplitter(routes.PluginHistoryHtml, routes.PluginHistoryJson))
 	app.Get("/plugin/*", middleware.ParseRepositoryParam, middleware.RouteSplitter(routes.PluginHtml, routes.PluginJson))
 	app.Get("/plugins/*", middleware.ParseRepositoryParam, middleware.RouteSplitter(routes.OnlyAcceptsJsonError, routes.SearchPluginsByName))
 	app.Get("/authors/*", middleware.ParseRepositoryParam, middleware.RouteSplitter(routes.OnlyAcceptsJsonError, routes.SearchPluginsByAuthor))
 	app.Get("/changelog/*", middleware.ParseRepositoryParam, middleware.RouteSplitter(routes.ChangelogHtml, routes.ChangelogJson))
 
+	app.Get("/feeds", routes.FeedsJson)
+	app.Get("/feeds/*", middleware.ParseRepositoryParam, routes.FeedJson)
+
+	app.Get("/sources.json", routes.SourcesJson)
+	app.Get("/sources", middleware.RouteSplitter(routes.SourcesHtml, routes.SourcesJson))
+
 	app.Get("/", middleware.RouteSplitter(routes.HomepageHtml, routes.HomepageJson))
 
+	api := app.Group("/api/v1", middleware.CountApiRequest)
+
+	api.Get("/plugins", routes.ApiListPlugins)
+	api.Get("/plugins/:name", middleware.ParseRepositoryNameParam, routes.ApiGetPlugin)
+	api.Get("/authors", routes.ApiListAuthors)
+	api.Get("/tags", routes.ApiListTags)
+	api.Get("/sources", routes.ApiListSources)
+	api.Get("/changelog/*", middleware.ParseRepositoryParam, routes.ApiGetChangelog)
+	api.Use(routes.ApiNotFound)
+
 	hx := app.Group("/hx")
 
 	hx.Get("/plugins", routes.RenderPluginListComponent)
 
+	admin := app.Group("/admin/api", middleware.AdminAuth)
+
+	admin.Get("/sources", routes.AdminListSources)
+	admin.Post("/sources", routes.AdminAddSource)
+	admin.Delete("/sources", routes.AdminRemoveSource)
+	admin.Post("/sources/enable", routes.AdminEnableSource)
+	admin.Post("/sources/disable", routes.AdminDisableSource)
+	admin.Post("/sources/refresh", routes.AdminRefreshSource)
+	admin.Get("/plugins", routes.AdminListPlugins)
+	admin.Post("/plugins/refresh", routes.AdminRefreshPlugin)
+	admin.Post("/plugins/pin", routes.AdminPinPlugin)
+	admin.Post("/plugins/unpin", routes.AdminUnpinPlugin)
+	admin.Delete("/repositories", routes.AdminPurgeRepository)
+	admin.Get("/jobs", routes.AdminListJobs)
+	admin.Get("/conflicts", routes.AdminListConflicts)
+	admin.Get("/blocked", routes.AdminListBlocked)
+	admin.Get("/overrides", routes.AdminListOverrides)
+	admin.Post("/overrides", routes.AdminSetOverride)
+	admin.Delete("/overrides", routes.AdminRemoveOverride)
+
 	app.Use(routes.NotFound)
 
 	app.Listen(addr)
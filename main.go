@@ -7,23 +7,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/senither/dalamud-plugin-listing/cli"
 	"github.com/senither/dalamud-plugin-listing/cron"
 	"github.com/senither/dalamud-plugin-listing/http"
 )
 
 func main() {
+	os.Exit(cli.Run(os.Args[1:], serve))
+}
+
+func serve() {
 	runningCh := make(chan struct{}, 1)
 	shutdownCh := make(chan os.Signal, 1)
 
-	if os.Getenv("APP_URL") == "" {
-		slog.Warn("APP_URL environment variable is not set, defaulting to http://localhost:8080/")
-		os.Setenv("APP_URL", "http://localhost:8080/")
-	}
-
-	if os.Getenv("GITHUB_TOKEN") == "" {
-		slog.Warn("GITHUB_TOKEN environment variable is not set, some features may not work properly")
-	}
-
 	signal.Notify(shutdownCh, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 
 	go func() {
@@ -31,7 +27,7 @@ func main() {
 		slog.Info("Shutting down server gracefully")
 
 		slog.Info("Stopping all running jobs...")
-		cron.ShutdownJobs()
+		cron.ShutdownJobs(8 * time.Second)
 
 		slog.Info("Shutting down the HTTP server...")
 		http.ShutdownServer()
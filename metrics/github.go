@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	githubRateLimitGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "github_rate_limit",
+		Help: "The GitHub API rate limit as reported by the most recent response.",
+	}, []string{"type"})
+
+	githubRateLimitResetGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "github_rate_limit_reset_timestamp_seconds",
+		Help: "The Unix timestamp for when the GitHub API rate limit resets.",
+	})
+
+	githubRequestCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_request_total",
+		Help: "The total number of requests sent to the GitHub API.",
+	}, []string{"outcome"})
+)
+
+type GitHubRequestOutcome string
+
+const (
+	GitHubRequestSuccess     GitHubRequestOutcome = "success"
+	GitHubRequestRetried     GitHubRequestOutcome = "retried"
+	GitHubRequestFailed      GitHubRequestOutcome = "failed"
+	GitHubRequestRateLimited GitHubRequestOutcome = "rate_limited"
+)
+
+func SetGitHubRateLimit(limit int, remaining int, reset time.Time) {
+	githubRateLimitGauge.WithLabelValues("limit").Set(float64(limit))
+	githubRateLimitGauge.WithLabelValues("remaining").Set(float64(remaining))
+	githubRateLimitResetGauge.Set(float64(reset.Unix()))
+}
+
+func IncrementGitHubRequestCounter(outcome GitHubRequestOutcome) {
+	githubRequestCounter.WithLabelValues(string(outcome)).Inc()
+}
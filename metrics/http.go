@@ -17,6 +17,7 @@ type RouteMetric string
 const (
 	HtmlRoute RouteMetric = "html"
 	JsonRoute RouteMetric = "json"
+	ApiRoute  RouteMetric = "api"
 )
 
 func IncrementRouteRequestCounter(route RouteMetric) {
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	sourceFetchCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "source_fetch_total",
+		Help: "The total number of repository source fetches.",
+	}, []string{"outcome"})
+
+	sourceFailuresGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "source_consecutive_failures",
+		Help: "The number of failed fetches in a row for a repository source.",
+	}, []string{"url"})
+
+	sourceCircuitGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "source_circuit_state",
+		Help: "The circuit breaker state for a repository source, the current state is set to 1.",
+	}, []string{"url", "state"})
+)
+
+type SourceFetchOutcome string
+
+const (
+	SourceFetchSuccess SourceFetchOutcome = "success"
+	SourceFetchFailed  SourceFetchOutcome = "failed"
+	SourceFetchSkipped SourceFetchOutcome = "skipped"
+)
+
+var circuitStates = []string{"closed", "open", "half-open"}
+
+func IncrementSourceFetchCounter(outcome SourceFetchOutcome) {
+	sourceFetchCounter.WithLabelValues(string(outcome)).Inc()
+}
+
+func SetSourceCircuitState(url string, state string, failures int) {
+	sourceFailuresGauge.WithLabelValues(url).Set(float64(failures))
+
+	for _, s := range circuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+
+		sourceCircuitGauge.WithLabelValues(url, s).Set(value)
+	}
+}
+
+// DeleteSourceMetrics removes the metrics for a source that was removed.
+func DeleteSourceMetrics(url string) {
+	sourceFailuresGauge.DeleteLabelValues(url)
+
+	for _, s := range circuitStates {
+		sourceCircuitGauge.DeleteLabelValues(url, s)
+	}
+}
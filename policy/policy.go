@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// Actions a rule can take, the first rule that matches a plugin entry decides
+// if it's allowed, entries that match no rule get the default action.
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// Rule matches plugin entries by every criteria that is set, the pattern is a
+// regular expression matched against both the InternalName and the Name.
+type Rule struct {
+	Action       string
+	InternalName string
+	Author       string
+	Source       string
+	Tag          string
+	Pattern      string
+	Reason       string
+
+	pattern *regexp.Regexp
+}
+
+// Policy decides which plugin entries from sources are served.
+type Policy struct {
+	Default string
+	Rules   []Rule
+}
+
+// IsAction returns true if the value is an action a rule can take.
+func IsAction(value string) bool {
+	return value == ActionAllow || value == ActionDeny
+}
+
+// New compiles the rules into a policy, an empty default action allows every
+// entry that doesn't match a rule.
+func New(defaultAction string, rules []Rule) (*Policy, error) {
+	if defaultAction == "" {
+		defaultAction = ActionAllow
+	}
+
+	var errs []error
+
+	if !IsAction(defaultAction) {
+		errs = append(errs, fmt.Errorf("unknown default action %q, expected allow or deny", defaultAction))
+	}
+
+	compiled := make([]Rule, len(rules))
+	for i, rule := range rules {
+		if !IsAction(rule.Action) {
+			errs = append(errs, fmt.Errorf("rule #%d has an unknown action %q, expected allow or deny", i, rule.Action))
+		}
+
+		if rule.InternalName == "" && rule.Author == "" && rule.Source == "" && rule.Tag == "" && rule.Pattern == "" {
+			errs = append(errs, fmt.Errorf("rule #%d must match by internal_name, author, source, tag or pattern", i))
+		}
+
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule #%d has an invalid pattern: %w", i, err))
+			}
+
+			rule.pattern = pattern
+		}
+
+		compiled[i] = rule
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return &Policy{Default: defaultAction, Rules: compiled}, nil
+}
+
+// Evaluate returns if the plugin entry is allowed, along with the reason of
+// the rule that denied it.
+func (p *Policy) Evaluate(repo state.Repository) (bool, string) {
+	for _, rule := range p.Rules {
+		if !rule.Matches(repo) {
+			continue
+		}
+
+		if rule.Action == ActionAllow {
+			return true, ""
+		}
+
+		if rule.Reason == "" {
+			return false, "Denied by the policy"
+		}
+
+		return false, rule.Reason
+	}
+
+	if p.Default == ActionDeny {
+		return false, "Not allowed by the policy"
+	}
+
+	return true, ""
+}
+
+// Matches returns true if every criteria set on the rule matches the entry.
+func (r Rule) Matches(repo state.Repository) bool {
+	if r.InternalName != "" && !strings.EqualFold(r.InternalName, repo.InternalName) {
+		return false
+	}
+
+	if r.Author != "" && !containsFold(strings.Split(repo.Author, ","), r.Author) {
+		return false
+	}
+
+	if r.Source != "" && r.Source != repo.RepositoryOrigin.RepositoryUrl {
+		return false
+	}
+
+	if r.Tag != "" && !containsFold(repo.Tags, r.Tag) {
+		return false
+	}
+
+	if r.pattern != nil && !r.pattern.MatchString(repo.InternalName) && !r.pattern.MatchString(repo.Name) {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func TestFirstMatchingRuleDecides(t *testing.T) {
+	p, err := New(ActionDeny, []Rule{
+		{Action: ActionDeny, InternalName: "Copycat", Reason: "Duplicates an official plugin"},
+		{Action: ActionAllow, Author: "senither"},
+		{Action: ActionDeny, Tag: "nsfw", Source: "https://example.com/repo.json"},
+		{Action: ActionAllow, Pattern: "^Good"},
+	})
+
+	if err != nil {
+		t.Fatalf("Expected the policy to compile, got %v", err)
+	}
+
+	repo := func(internalName string, author string, tags ...string) state.Repository {
+		r := state.Repository{InternalName: internalName, Name: internalName, Author: author, Tags: tags}
+		r.RepositoryOrigin.RepositoryUrl = "https://example.com/repo.json"
+
+		return r
+	}
+
+	cases := []struct {
+		repo    state.Repository
+		allowed bool
+		reason  string
+	}{
+		{repo("Copycat", "Senither"), false, "Duplicates an official plugin"},
+		{repo("Plugin", "Someone, Senither"), true, ""},
+		{repo("GoodPlugin", "Someone", "NSFW"), false, "Denied by the policy"},
+		{repo("GoodPlugin", "Someone"), true, ""},
+		{repo("Unknown", "Someone"), false, "Not allowed by the policy"},
+	}
+
+	for _, c := range cases {
+		allowed, reason := p.Evaluate(c.repo)
+		if allowed != c.allowed || reason != c.reason {
+			t.Errorf("Expected %s to be allowed=%t (%q), got allowed=%t (%q)", c.repo.InternalName, c.allowed, c.reason, allowed, reason)
+		}
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	if _, err := New("block", nil); err == nil {
+		t.Error("Expected an unknown default action to fail")
+	}
+
+	if _, err := New(ActionAllow, []Rule{{Action: ActionDeny}}); err == nil {
+		t.Error("Expected a rule without criteria to fail")
+	}
+
+	if _, err := New(ActionAllow, []Rule{{Action: ActionDeny, Pattern: "("}}); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+}
@@ -0,0 +1,332 @@
+package state
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Reasons a plugin entry can lose to another entry with the same InternalName,
+// internal plugins always win, followed by the entry from the source with the
+// highest priority and then the entry with the highest assembly version. Ties
+// are won by the entry that was already being served.
+const (
+	ConflictReasonInternal = "internal"
+	ConflictReasonPriority = "priority"
+	ConflictReasonVersion  = "version"
+	ConflictReasonExisting = "existing"
+)
+
+// ShadowedRepository is a plugin entry that is hidden by another entry with
+// the same InternalName from a different source.
+type ShadowedRepository struct {
+	Repository Repository
+	ShadowedBy string
+	Reason     string
+}
+
+// shadowed holds the entries that lost the conflict resolution, they're kept
+// in memory so they can take over when the served entry is removed. It's
+// guarded by repositoriesMu like the repositories slice.
+var (
+	shadowed       []Repository
+	sourcePriority = func(url string) int { return 0 }
+)
+
+// SetSourcePriority sets the function used to look up the priority of the
+// source a plugin entry came from, a higher priority wins conflicts.
+func SetSourcePriority(priority func(url string) int) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	sourcePriority = priority
+}
+
+// GetShadowedRepositories returns every entry that is hidden by another entry
+// with the same InternalName, along with the source of the served entry.
+func GetShadowedRepositories() []ShadowedRepository {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	result := make([]ShadowedRepository, 0, len(shadowed))
+	for _, repo := range shadowed {
+		entry := ShadowedRepository{Repository: repo}
+
+		if index := getRepositoryIndex(repo); index != -1 {
+			winner := repositories[index]
+
+			entry.ShadowedBy = winner.RepositoryOrigin.RepositoryUrl
+			entry.Reason = conflictReason(winner, repo)
+		}
+
+		entry.Repository.Tags = slices.Clone(repo.Tags)
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+// setRepository adds the entry, or replaces the entry from the same source,
+// then decides which of the entries sharing its InternalName is served. The
+// served entry is persisted if persist is set, the shadowed entries are only
+// kept in memory.
+func setRepository(repo Repository, persist bool) {
+	index := getRepositoryIndex(repo)
+	if index == -1 {
+		repositories = append(repositories, repo)
+
+		if persist {
+			saveRepository(repo)
+		}
+
+		return
+	}
+
+	current := repositories[index]
+
+	candidates := append([]Repository{current}, takeShadowed(repo)...)
+	if i := slices.IndexFunc(candidates, func(candidate Repository) bool {
+		return isSameEntry(candidate, repo)
+	}); i != -1 {
+		candidates[i] = repo
+	} else {
+		candidates = append(candidates, repo)
+	}
+
+	winner := resolveConflict(index, candidates)
+
+	if current.RepositoryOrigin.RepositoryUrl != winner.RepositoryOrigin.RepositoryUrl {
+		slog.Info("Plugin is provided by another source now",
+			"internalName", winner.InternalName,
+			"previous", current.RepositoryOrigin.RepositoryUrl,
+			"current", winner.RepositoryOrigin.RepositoryUrl,
+		)
+	}
+
+	if !persist {
+		return
+	}
+
+	// The served entry is unchanged if a shadowed entry was updated.
+	if isSameEntry(winner, current) && !isSameEntry(winner, repo) {
+		return
+	}
+
+	// The store is keyed by the name and author, a source can change those
+	// while keeping the InternalName.
+	if repositoryKey(current) != repositoryKey(winner) {
+		deletePersistedRepository(current)
+	}
+
+	saveRepository(winner)
+}
+
+// deleteRepository removes the entry from the same source as the given entry,
+// the best shadowed entry takes over when the served entry is removed.
+func deleteRepository(repo Repository) bool {
+	if i := slices.IndexFunc(shadowed, func(candidate Repository) bool {
+		return isSameEntry(candidate, repo)
+	}); i != -1 {
+		removed := shadowed[i]
+		shadowed = slices.Delete(shadowed, i, i+1)
+
+		if index := getRepositoryIndex(removed); index == -1 || repositoryKey(repositories[index]) != repositoryKey(removed) {
+			deletePersistedRepository(removed)
+		}
+
+		return true
+	}
+
+	index := getRepositoryIndex(repo)
+	if index == -1 || !isSameEntry(repositories[index], repo) {
+		return false
+	}
+
+	removed := repositories[index]
+	deletePersistedRepository(removed)
+
+	candidates := takeShadowed(removed)
+	if len(candidates) == 0 {
+		repositories = slices.Delete(repositories, index, index+1)
+
+		// The plugin is gone from every source, so a pin must not be
+		// applied if it's ever added again.
+		dropHistory(removed)
+
+		return true
+	}
+
+	winner := resolveConflict(index, candidates)
+	saveRepository(winner)
+
+	slog.Info("Plugin is provided by another source now",
+		"internalName", winner.InternalName,
+		"previous", removed.RepositoryOrigin.RepositoryUrl,
+		"current", winner.RepositoryOrigin.RepositoryUrl,
+	)
+
+	return true
+}
+
+// resolveConflict serves the best candidate at the index in the repositories
+// slice and shadows the rest, the first candidate wins ties.
+func resolveConflict(index int, candidates []Repository) Repository {
+	best := 0
+	for i := 1; i < len(candidates); i++ {
+		if outranks(candidates[i], candidates[best]) {
+			best = i
+		}
+	}
+
+	repositories[index] = candidates[best]
+
+	for i, candidate := range candidates {
+		if i != best {
+			shadowed = append(shadowed, candidate)
+		}
+	}
+
+	return candidates[best]
+}
+
+// takeShadowed removes and returns the shadowed entries that conflict with
+// the given entry.
+func takeShadowed(repo Repository) []Repository {
+	var taken []Repository
+
+	shadowed = slices.DeleteFunc(shadowed, func(candidate Repository) bool {
+		if conflictKey(candidate) != conflictKey(repo) {
+			return false
+		}
+
+		taken = append(taken, candidate)
+		return true
+	})
+
+	return taken
+}
+
+func outranks(a Repository, b Repository) bool {
+	if aInternal, bInternal := isInternalRepository(a), isInternalRepository(b); aInternal != bInternal {
+		return aInternal
+	}
+
+	aPriority := sourcePriority(a.RepositoryOrigin.RepositoryUrl)
+	bPriority := sourcePriority(b.RepositoryOrigin.RepositoryUrl)
+	if aPriority != bPriority {
+		return aPriority > bPriority
+	}
+
+	return a.AssemblyVersion.Compare(b.AssemblyVersion) > 0
+}
+
+func conflictReason(winner Repository, loser Repository) string {
+	switch {
+	case isInternalRepository(winner) != isInternalRepository(loser):
+		return ConflictReasonInternal
+	case sourcePriority(winner.RepositoryOrigin.RepositoryUrl) != sourcePriority(loser.RepositoryOrigin.RepositoryUrl):
+		return ConflictReasonPriority
+	case winner.AssemblyVersion.Compare(loser.AssemblyVersion) != 0:
+		return ConflictReasonVersion
+	default:
+		return ConflictReasonExisting
+	}
+}
+
+// conflictKey is the key entries conflict on, Dalamud identifies plugins by
+// their InternalName so only one entry per InternalName can be served.
+func conflictKey(repo Repository) string {
+	if repo.InternalName == "" {
+		return repositoryKey(repo)
+	}
+
+	return strings.ToLower(repo.InternalName)
+}
+
+func isSameEntry(a Repository, b Repository) bool {
+	return conflictKey(a) == conflictKey(b) &&
+		a.RepositoryOrigin.RepositoryUrl == b.RepositoryOrigin.RepositoryUrl
+}
+
+func isInternalRepository(repo Repository) bool {
+	return repo.RepositoryOrigin.IsInternalPlugin != nil && *repo.RepositoryOrigin.IsInternalPlugin
+}
+
+func saveRepository(repo Repository) {
+	if err := store.SaveRepository(repo); err != nil {
+		slog.Error("Failed to persist repository",
+			"err", err,
+			"repository", repo.Name,
+		)
+	}
+}
+
+func deletePersistedRepository(repo Repository) {
+	if err := store.DeleteRepository(repo); err != nil {
+		slog.Error("Failed to delete persisted repository",
+			"err", err,
+			"repository", repo.Name,
+		)
+	}
+}
+
+// PluginOrigin is a source that provides a plugin, the served origin is the
+// one that won the conflict resolution. An origin is stale if another origin
+// provides a newer version of the plugin.
+type PluginOrigin struct {
+	RepositoryUrl    string
+	AssemblyVersion  Version
+	DalamudApiLevel  ApiLevel
+	LastSeenAt       int64
+	IsServed         bool
+	IsInternalPlugin bool
+	IsStale          bool
+}
+
+// GetRepositoryOrigins returns every source that provides the plugin with the
+// given InternalName, the served origin is always first followed by the
+// shadowed origins with the newest version first.
+func GetRepositoryOrigins(internalName string) []PluginOrigin {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	key := Repository{InternalName: internalName}
+
+	index := getRepositoryIndex(key)
+	if index == -1 {
+		return nil
+	}
+
+	entries := []Repository{repositories[index]}
+	for _, repo := range shadowed {
+		if conflictKey(repo) == conflictKey(key) {
+			entries = append(entries, repo)
+		}
+	}
+
+	slices.SortStableFunc(entries[1:], func(a, b Repository) int {
+		return b.AssemblyVersion.Compare(a.AssemblyVersion)
+	})
+
+	newest := entries[0].AssemblyVersion
+	for _, repo := range entries[1:] {
+		if repo.AssemblyVersion.Compare(newest) > 0 {
+			newest = repo.AssemblyVersion
+		}
+	}
+
+	origins := make([]PluginOrigin, len(entries))
+	for i, repo := range entries {
+		origins[i] = PluginOrigin{
+			RepositoryUrl:    repo.RepositoryOrigin.RepositoryUrl,
+			AssemblyVersion:  repo.AssemblyVersion,
+			DalamudApiLevel:  repo.DalamudApiLevel,
+			LastSeenAt:       repo.RepositoryOrigin.LastUpdatedAt,
+			IsServed:         i == 0,
+			IsInternalPlugin: isInternalRepository(repo),
+			IsStale:          repo.AssemblyVersion.Compare(newest) < 0,
+		}
+	}
+
+	return origins
+}
@@ -0,0 +1,77 @@
+package state
+
+import "testing"
+
+func conflictingRepository(origin string, version Version) Repository {
+	return Repository{
+		Name:            "Plugin",
+		Author:          "Author from " + origin,
+		InternalName:    "Plugin",
+		AssemblyVersion: version,
+		RepositoryOrigin: RepositoryOrigin{
+			RepositoryUrl: origin,
+		},
+	}
+}
+
+func TestConflictsAreResolvedByInternalName(t *testing.T) {
+	useNopStore(t)
+
+	priorities := map[string]int{"https://priority.example.com": 10}
+	previous := sourcePriority
+	SetSourcePriority(func(url string) int { return priorities[url] })
+	t.Cleanup(func() { SetSourcePriority(previous) })
+
+	UpsertRepository(conflictingRepository("https://old.example.com", "1.0.0.0"))
+	UpsertRepository(conflictingRepository("https://new.example.com", "1.1.0.0"))
+
+	served := GetRepositories()
+	if len(served) != 1 || served[0].RepositoryOrigin.RepositoryUrl != "https://new.example.com" {
+		t.Fatalf("Expected the highest version to be served, got %+v", served)
+	}
+
+	UpsertRepository(conflictingRepository("https://priority.example.com", "0.9.0.0"))
+
+	if served := GetRepositories(); served[0].RepositoryOrigin.RepositoryUrl != "https://priority.example.com" {
+		t.Fatalf("Expected the source with the highest priority to be served, got %+v", served)
+	}
+
+	truthy := true
+	internal := conflictingRepository("https://github.com/Senither/Plugin", "0.1.0.0")
+	internal.RepositoryOrigin.IsInternalPlugin = &truthy
+	UpsertRepository(internal)
+
+	if served := GetRepositories(); served[0].RepositoryOrigin.RepositoryUrl != "https://github.com/Senither/Plugin" {
+		t.Fatalf("Expected the internal plugin to be served, got %+v", served)
+	}
+
+	origins := GetRepositoryOrigins("plugin")
+	if len(origins) != 4 || !origins[0].IsServed || !origins[0].IsInternalPlugin || origins[1].RepositoryUrl != "https://new.example.com" {
+		t.Fatalf("Expected the served origin first followed by the newest versions, got %+v", origins)
+	}
+
+	if !origins[0].IsStale || origins[1].IsStale {
+		t.Errorf("Expected origins behind the newest version to be stale, got %+v", origins)
+	}
+
+	reasons := make(map[string]string)
+	for _, entry := range GetShadowedRepositories() {
+		reasons[entry.Repository.RepositoryOrigin.RepositoryUrl] = entry.Reason
+	}
+
+	if len(reasons) != 3 || reasons["https://priority.example.com"] != ConflictReasonInternal {
+		t.Errorf("Expected the other sources to be shadowed by the internal plugin, got %v", reasons)
+	}
+
+	DeleteRepository(internal)
+	DeleteRepositoriesByOriginUrl("https://priority.example.com")
+
+	served = GetRepositories()
+	if len(served) != 1 || served[0].RepositoryOrigin.RepositoryUrl != "https://new.example.com" {
+		t.Fatalf("Expected the best remaining entry to take over, got %+v", served)
+	}
+
+	if shadowed := GetShadowedRepositories(); len(shadowed) != 1 || shadowed[0].Reason != ConflictReasonVersion {
+		t.Errorf("Expected the older entry to stay shadowed, got %+v", shadowed)
+	}
+}
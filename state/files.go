@@ -0,0 +1,175 @@
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const backupSuffix = ".bak"
+
+// cacheEnvelope wraps the cached data with a checksum so a truncated or
+// partially written file can be detected when it's loaded again.
+type cacheEnvelope struct {
+	Checksum string          `json:"sha256"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// writeCacheFile atomically replaces the file at the given path, the content
+// is written to a temporary file and synced before the current file is kept
+// as the backup generation and the temporary file is renamed into place. A
+// current file that fails verification is replaced without touching the
+// backup, so the last good generation is never lost.
+func writeCacheFile(path string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(data)
+	content, err := json.Marshal(cacheEnvelope{
+		Checksum: hex.EncodeToString(checksum[:]),
+		Data:     data,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	var current json.RawMessage
+	if err := readVerifiedFile(path, &current); err == nil {
+		if err := os.Rename(path, path+backupSuffix); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Replacing corrupt cache file without keeping it as the backup",
+			"err", err,
+			"path", path,
+		)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// readCacheFile loads the file at the given path into the value, falling back
+// to the backup generation if the current file is missing or corrupt. Only
+// when neither generation can be used is an error returned, a fresh install
+// without any files is not considered an error.
+func readCacheFile(path string, value any) error {
+	var errs []error
+
+	for _, candidate := range []string{path, path + backupSuffix} {
+		err := readVerifiedFile(candidate, value)
+		if err == nil {
+			if candidate != path {
+				slog.Warn("Recovered cache file from the previous generation",
+					"path", path,
+					"backup", candidate,
+				)
+			}
+
+			return nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("Failed to read cache file",
+				"err", err,
+				"path", candidate,
+			)
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReadRepositoriesFile reads the plugins from a repositories cache file, like
+// the one written by the JSON store. Unlike when the cache is loaded, there's
+// no fallback to the backup generation.
+func ReadRepositoriesFile(path string) ([]Repository, error) {
+	var repos []Repository
+	if err := readVerifiedFile(path, &repos); err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+func readVerifiedFile(path string, value any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Files written before checksums were introduced contain the raw JSON
+	// array, they're still accepted as long as they can be decoded.
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, value)
+	}
+
+	var envelope cacheEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(envelope.Data)
+	if hex.EncodeToString(checksum[:]) != envelope.Checksum {
+		return fmt.Errorf("checksum mismatch in %s", path)
+	}
+
+	return json.Unmarshal(envelope.Data, value)
+}
+
+func syncDir(dir string) {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	defer handle.Close()
+
+	// Syncing a directory isn't supported on every platform, the rename has
+	// already happened so a failure here only weakens the durability.
+	_ = handle.Sync()
+}
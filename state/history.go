@@ -0,0 +1,245 @@
+package state
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxHistoryEntries is the number of entries kept per plugin, the oldest
+// entries are dropped first.
+const maxHistoryEntries = 10
+
+var (
+	ErrHistoryNotFound      = errors.New("no history was found for the plugin")
+	ErrHistoryEntryNotFound = errors.New("no history entry was found for the version")
+)
+
+// HistoryEntry is a manifest entry that was served for a plugin, the first
+// and last seen timestamps are when a source first and last provided it.
+type HistoryEntry struct {
+	Repository  Repository `json:"Repository"`
+	FirstSeenAt int64      `json:"FirstSeenAt"`
+	LastSeenAt  int64      `json:"LastSeenAt"`
+}
+
+// PluginHistory holds the previous entries of a third-party plugin with the
+// newest entry first. A pinned entry is served instead of the latest entry
+// until the plugin is unpinned.
+type PluginHistory struct {
+	InternalName string         `json:"InternalName"`
+	Entries      []HistoryEntry `json:"Entries"`
+	Pinned       *HistoryEntry  `json:"Pinned,omitempty"`
+}
+
+// histories is keyed by the conflict key of the plugin and guarded by
+// repositoriesMu like the repositories slice.
+var histories = make(map[string]*PluginHistory)
+
+// GetPluginHistory returns a copy of the history for the plugin with the given
+// InternalName, or nil if no entries have been recorded for it.
+func GetPluginHistory(internalName string) *PluginHistory {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	history, ok := histories[conflictKey(Repository{InternalName: internalName})]
+	if !ok {
+		return nil
+	}
+
+	return copyHistory(history)
+}
+
+// PinRepositoryVersion serves the history entry with the given version instead
+// of the latest entry, the url is only needed if several sources provided the
+// same version.
+func PinRepositoryVersion(internalName string, version string, url string) (HistoryEntry, error) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	history, ok := histories[conflictKey(Repository{InternalName: internalName})]
+	if !ok {
+		return HistoryEntry{}, ErrHistoryNotFound
+	}
+
+	index := slices.IndexFunc(history.Entries, func(entry HistoryEntry) bool {
+		return entry.Repository.AssemblyVersion.String() == version &&
+			(url == "" || entry.Repository.RepositoryOrigin.RepositoryUrl == url)
+	})
+
+	if index == -1 {
+		return HistoryEntry{}, ErrHistoryEntryNotFound
+	}
+
+	pinned := history.Entries[index]
+	history.Pinned = &pinned
+
+	saveHistory(history)
+	repositoryLastUpdatedAt = time.Now().Unix()
+
+	slog.Info("Pinned plugin to a previous version",
+		"internalName", history.InternalName,
+		"version", version,
+		"url", pinned.Repository.RepositoryOrigin.RepositoryUrl,
+	)
+
+	return pinned, nil
+}
+
+// UnpinRepository serves the latest entry for the plugin again, it returns
+// false if the plugin wasn't pinned.
+func UnpinRepository(internalName string) bool {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	history, ok := histories[conflictKey(Repository{InternalName: internalName})]
+	if !ok || history.Pinned == nil {
+		return false
+	}
+
+	history.Pinned = nil
+
+	saveHistory(history)
+	repositoryLastUpdatedAt = time.Now().Unix()
+
+	slog.Info("Unpinned plugin", "internalName", history.InternalName)
+
+	return true
+}
+
+// recordHistory adds the entry to the history of the plugin, or refreshes the
+// last seen timestamp if the source already provided the same version.
+// Internal plugins are skipped since their releases are kept separately.
+func recordHistory(repo Repository) {
+	if isInternalRepository(repo) || repo.InternalName == "" {
+		return
+	}
+
+	key := conflictKey(repo)
+	now := time.Now().Unix()
+
+	history, ok := histories[key]
+	if !ok {
+		history = &PluginHistory{InternalName: repo.InternalName}
+		histories[key] = history
+	}
+
+	repo.Tags = slices.Clone(repo.Tags)
+
+	index := slices.IndexFunc(history.Entries, func(entry HistoryEntry) bool {
+		return isSameHistoryEntry(entry.Repository, repo)
+	})
+
+	if index != -1 {
+		history.Entries[index].Repository = repo
+		history.Entries[index].LastSeenAt = now
+
+		saveHistory(history)
+		return
+	}
+
+	history.Entries = slices.Insert(history.Entries, 0, HistoryEntry{
+		Repository:  repo,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	})
+
+	if len(history.Entries) > maxHistoryEntries {
+		history.Entries = history.Entries[:maxHistoryEntries]
+	}
+
+	if history.Pinned != nil {
+		slog.Info("Plugin is pinned, the new version will not be served",
+			"internalName", repo.InternalName,
+			"version", repo.AssemblyVersion.String(),
+			"pinned", history.Pinned.Repository.AssemblyVersion.String(),
+		)
+	}
+
+	saveHistory(history)
+}
+
+// applyPin returns the pinned entry in place of the served entry, the origin
+// of the served entry is kept so it still expires with its source.
+func applyPin(repo Repository) Repository {
+	history, ok := histories[conflictKey(repo)]
+	if !ok || history.Pinned == nil {
+		return repo
+	}
+
+	pinned := history.Pinned.Repository
+	pinned.Tags = slices.Clone(pinned.Tags)
+	pinned.RepositoryOrigin = repo.RepositoryOrigin
+
+	return pinned
+}
+
+// dropHistory forgets the history and the pin of the plugin.
+func dropHistory(repo Repository) {
+	history, ok := histories[conflictKey(repo)]
+	if !ok {
+		return
+	}
+
+	delete(histories, conflictKey(repo))
+
+	if err := store.DeleteHistory(history.InternalName); err != nil {
+		slog.Error("Failed to delete persisted plugin history",
+			"err", err,
+			"internalName", history.InternalName,
+		)
+	}
+
+	if history.Pinned != nil {
+		slog.Info("Unpinned plugin that is no longer provided by any source",
+			"internalName", history.InternalName,
+		)
+	}
+}
+
+func isSameHistoryEntry(a Repository, b Repository) bool {
+	return a.AssemblyVersion.Compare(b.AssemblyVersion) == 0 &&
+		strings.EqualFold(a.RepositoryOrigin.RepositoryUrl, b.RepositoryOrigin.RepositoryUrl)
+}
+
+func copyHistory(history *PluginHistory) *PluginHistory {
+	result := &PluginHistory{
+		InternalName: history.InternalName,
+		Entries:      make([]HistoryEntry, len(history.Entries)),
+	}
+
+	for i, entry := range history.Entries {
+		entry.Repository.Tags = slices.Clone(entry.Repository.Tags)
+		result.Entries[i] = entry
+	}
+
+	if history.Pinned != nil {
+		pinned := *history.Pinned
+		pinned.Repository.Tags = slices.Clone(pinned.Repository.Tags)
+		result.Pinned = &pinned
+	}
+
+	return result
+}
+
+func saveHistory(history *PluginHistory) {
+	if err := store.SaveHistory(*copyHistory(history)); err != nil {
+		slog.Error("Failed to persist plugin history",
+			"err", err,
+			"internalName", history.InternalName,
+		)
+	}
+}
+
+func loadCachedHistories() {
+	cached, err := store.LoadHistories()
+	if err != nil {
+		slog.Error("Failed to load cached plugin history, starting without it", "err", err)
+	}
+
+	for _, history := range cached {
+		histories[conflictKey(Repository{InternalName: history.InternalName})] = &history
+	}
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPluginHistoryIsBoundedAndCanBePinned(t *testing.T) {
+	useNopStore(t)
+
+	origin := "https://example.com/repo.json"
+
+	for minor := range maxHistoryEntries + 2 {
+		UpsertRepository(conflictingRepository(origin, Version(fmt.Sprintf("1.%d.0.0", minor))))
+	}
+
+	UpsertRepository(conflictingRepository(origin, "2.0.0.0"))
+	UpsertRepository(conflictingRepository(origin, "2.0.0.0"))
+
+	history := GetPluginHistory("plugin")
+	if history == nil || len(history.Entries) != maxHistoryEntries {
+		t.Fatalf("Expected the history to be capped at %d entries, got %+v", maxHistoryEntries, history)
+	}
+
+	if history.Entries[0].Repository.AssemblyVersion != "2.0.0.0" {
+		t.Errorf("Expected the newest entry first, got %s", history.Entries[0].Repository.AssemblyVersion)
+	}
+
+	if _, err := PinRepositoryVersion("Plugin", "9.9.9.9", ""); !errors.Is(err, ErrHistoryEntryNotFound) {
+		t.Errorf("Expected an unknown version to fail, got %v", err)
+	}
+
+	previous := history.Entries[1].Repository.AssemblyVersion
+	if _, err := PinRepositoryVersion("Plugin", previous.String(), origin); err != nil {
+		t.Fatalf("Expected the plugin to be pinned, got %v", err)
+	}
+
+	UpsertRepository(conflictingRepository(origin, "3.0.0.0"))
+
+	if served := GetRepositories(); len(served) != 1 || served[0].AssemblyVersion != previous {
+		t.Errorf("Expected the pinned version %s to be served, got %+v", previous, served)
+	}
+
+	if !UnpinRepository("Plugin") {
+		t.Fatal("Expected the plugin to be unpinned")
+	}
+
+	if served := GetRepositories(); served[0].AssemblyVersion != "3.0.0.0" {
+		t.Errorf("Expected the latest version to be served after unpinning, got %s", served[0].AssemblyVersion)
+	}
+}
+
+func TestPluginHistoryIsDroppedWhenThePluginIsRemoved(t *testing.T) {
+	useNopStore(t)
+
+	origin := "https://example.com/repo.json"
+
+	UpsertRepository(conflictingRepository(origin, "1.0.0.0"))
+	UpsertRepository(conflictingRepository(origin, "2.0.0.0"))
+
+	if _, err := PinRepositoryVersion("Plugin", "1.0.0.0", origin); err != nil {
+		t.Fatalf("Expected the plugin to be pinned, got %v", err)
+	}
+
+	DeleteRepository(conflictingRepository(origin, "2.0.0.0"))
+
+	if history := GetPluginHistory("Plugin"); history != nil {
+		t.Fatalf("Expected the history to be dropped with the plugin, got %+v", history)
+	}
+
+	UpsertRepository(conflictingRepository(origin, "3.0.0.0"))
+
+	if served := GetRepositories(); len(served) != 1 || served[0].AssemblyVersion != "3.0.0.0" {
+		t.Errorf("Expected the returning plugin to not be pinned, got %+v", served)
+	}
+}
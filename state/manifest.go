@@ -0,0 +1,296 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types in this file normalize the loosely typed fields found in plugin
+// manifests. Upstream repositories don't agree on whether numbers are sent as
+// numbers or strings, or whether timestamps are in seconds or milliseconds,
+// so every quirk is handled while decoding and the values are always encoded
+// in the shape Dalamud expects.
+
+// Version is an assembly version like "1.2.3.4", the raw value is kept as-is
+// so it's serialized exactly as the upstream repository sent it.
+type Version string
+
+// ApiLevel is the Dalamud API level a plugin was built against.
+type ApiLevel int
+
+// Count is a numeric counter, like the number of downloads for a plugin.
+type Count int64
+
+// Flag is a boolean that also accepts "true"/"false" strings and 0/1 numbers.
+type Flag bool
+
+// Timestamp is a point in time that is encoded as a Unix timestamp in seconds.
+type Timestamp struct {
+	time.Time
+}
+
+// millisecondThreshold is the point where a Unix timestamp is assumed to be
+// in milliseconds rather than seconds, in seconds it's roughly the year 33658.
+const millisecondThreshold = 1_000_000_000_000
+
+func (v *Version) UnmarshalJSON(data []byte) error {
+	raw, err := decodeScalar(data)
+	if err != nil {
+		return fmt.Errorf("invalid version %s: %w", data, err)
+	}
+
+	*v = Version(strings.TrimSpace(raw))
+	return nil
+}
+
+// Parts returns the numeric components of the version, any non-numeric
+// suffix on a component (like "-beta") is ignored.
+func (v Version) Parts() []int {
+	if v == "" {
+		return nil
+	}
+
+	raw := strings.TrimPrefix(strings.ToLower(string(v)), "v")
+	segments := strings.Split(raw, ".")
+
+	parts := make([]int, len(segments))
+	for i, segment := range segments {
+		end := strings.IndexFunc(segment, func(r rune) bool {
+			return r < '0' || r > '9'
+		})
+
+		if end != -1 {
+			segment = segment[:end]
+		}
+
+		parts[i], _ = strconv.Atoi(segment)
+	}
+
+	return parts
+}
+
+// Compare returns -1 if the version is older than the other version, 1 if it
+// is newer, and 0 if they're equal, missing components are treated as zero.
+func (v Version) Compare(other Version) int {
+	left, right := v.Parts(), other.Parts()
+
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r int
+		if i < len(left) {
+			l = left[i]
+		}
+
+		if i < len(right) {
+			r = right[i]
+		}
+
+		if l != r {
+			if l < r {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (v Version) String() string {
+	return string(v)
+}
+
+func (a *ApiLevel) UnmarshalJSON(data []byte) error {
+	value, err := decodeNumber(data)
+	if err != nil {
+		return fmt.Errorf("invalid API level %s: %w", data, err)
+	}
+
+	*a = ApiLevel(value)
+	return nil
+}
+
+func (c *Count) UnmarshalJSON(data []byte) error {
+	value, err := decodeNumber(data)
+	if err != nil {
+		return fmt.Errorf("invalid count %s: %w", data, err)
+	}
+
+	*c = Count(value)
+	return nil
+}
+
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	raw, err := decodeScalar(data)
+	if err != nil {
+		return fmt.Errorf("invalid flag %s: %w", data, err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "true", "1", "yes":
+		*f = true
+	case "false", "0", "no", "":
+		*f = false
+	default:
+		return fmt.Errorf("invalid flag %s", data)
+	}
+
+	return nil
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	raw, err := decodeScalar(data)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: %w", data, err)
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "0" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	if value, err := strconv.ParseFloat(raw, 64); err == nil {
+		*t = NewUnixTimestamp(int64(value))
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: %w", data, err)
+	}
+
+	*t = Timestamp{Time: parsed.UTC()}
+	return nil
+}
+
+// lenientFields are the manifest fields that fall back to their zero value
+// when they can't be parsed, keyed by the field name in the manifest.
+var lenientFields = map[string]func(data []byte) error{
+	"AssemblyVersion":        func(data []byte) error { var v Version; return v.UnmarshalJSON(data) },
+	"TestingAssemblyVersion": func(data []byte) error { var v Version; return v.UnmarshalJSON(data) },
+	"DalamudApiLevel":        func(data []byte) error { var v ApiLevel; return v.UnmarshalJSON(data) },
+	"TestingDalamudApiLevel": func(data []byte) error { var v ApiLevel; return v.UnmarshalJSON(data) },
+	"DownloadCount":          func(data []byte) error { var v Count; return v.UnmarshalJSON(data) },
+	"IsHide":                 func(data []byte) error { var v Flag; return v.UnmarshalJSON(data) },
+	"IsTestingExclusive":     func(data []byte) error { var v Flag; return v.UnmarshalJSON(data) },
+	"LastUpdated":            func(data []byte) error { var v Timestamp; return v.UnmarshalJSON(data) },
+	"LastUpdate":             func(data []byte) error { var v Timestamp; return v.UnmarshalJSON(data) },
+}
+
+// DecodeRepository decodes a single plugin entry from a manifest. Normalized
+// fields with a value that can't be parsed are left at their zero value and
+// returned as warnings, so the entry is only rejected by the validation.
+func DecodeRepository(data []byte) (Repository, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Repository{}, nil, err
+	}
+
+	var warnings []string
+	for key, value := range fields {
+		for name, parse := range lenientFields {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+
+			if err := parse(value); err != nil {
+				warnings = append(warnings, fmt.Sprintf("ignored invalid %s value %s", name, value))
+				delete(fields, key)
+			}
+		}
+	}
+
+	if len(warnings) > 0 {
+		cleaned, err := json.Marshal(fields)
+		if err != nil {
+			return Repository{}, nil, err
+		}
+
+		data = cleaned
+	}
+
+	var repo Repository
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return Repository{}, nil, err
+	}
+
+	slices.Sort(warnings)
+
+	return repo, warnings, nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+
+	return strconv.AppendInt(nil, t.Unix(), 10), nil
+}
+
+// NewUnixTimestamp creates a timestamp from a Unix timestamp that is either
+// in seconds or in milliseconds.
+func NewUnixTimestamp(value int64) Timestamp {
+	if value == 0 {
+		return Timestamp{}
+	}
+
+	if value >= millisecondThreshold || value <= -millisecondThreshold {
+		return Timestamp{Time: time.UnixMilli(value).UTC()}
+	}
+
+	return Timestamp{Time: time.Unix(value, 0).UTC()}
+}
+
+// decodeScalar returns the string form of a JSON string, number, boolean, or
+// null value, null values are returned as an empty string.
+func decodeScalar(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return "", nil
+
+	case data[0] == '"':
+		var value string
+		err := json.Unmarshal(data, &value)
+
+		return value, err
+
+	case data[0] == '{' || data[0] == '[':
+		return "", fmt.Errorf("expected a scalar value")
+	}
+
+	return string(data), nil
+}
+
+// decodeNumber returns the integer form of a JSON number or numeric string,
+// fractional values like 9.0 are truncated and empty values are zero.
+func decodeNumber(data []byte) (int64, error) {
+	raw, err := decodeScalar(data)
+	if err != nil {
+		return 0, err
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+
+	if value, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return value, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("expected a number")
+	}
+
+	return int64(value), nil
+}
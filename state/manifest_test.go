@@ -0,0 +1,201 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func decodeRepository(t *testing.T, raw string) Repository {
+	t.Helper()
+
+	var repo Repository
+	if err := json.Unmarshal([]byte(raw), &repo); err != nil {
+		t.Fatalf("Expected manifest to decode, got %v", err)
+	}
+
+	return repo
+}
+
+func TestApiLevelAcceptsNumbersAndStrings(t *testing.T) {
+	for _, raw := range []string{`12`, `12.0`, `"12"`, `" 12 "`} {
+		repo := decodeRepository(t, `{"DalamudApiLevel": `+raw+`}`)
+
+		if repo.DalamudApiLevel != 12 {
+			t.Errorf("Expected API level 12 from %s, got %d", raw, repo.DalamudApiLevel)
+		}
+	}
+}
+
+func TestApiLevelTreatsNullAndEmptyAsMissing(t *testing.T) {
+	for _, raw := range []string{`null`, `""`} {
+		repo := decodeRepository(t, `{"DalamudApiLevel": `+raw+`}`)
+
+		if repo.DalamudApiLevel != 0 {
+			t.Errorf("Expected API level to be missing from %s, got %d", raw, repo.DalamudApiLevel)
+		}
+	}
+}
+
+func TestApiLevelRejectsGarbage(t *testing.T) {
+	var repo Repository
+	if err := json.Unmarshal([]byte(`{"DalamudApiLevel": "twelve"}`), &repo); err == nil {
+		t.Errorf("Expected invalid API level to fail, got %d", repo.DalamudApiLevel)
+	}
+}
+
+func TestDownloadCountAcceptsNumbersAndStrings(t *testing.T) {
+	for _, raw := range []string{`1234`, `1234.0`, `"1234"`} {
+		repo := decodeRepository(t, `{"DownloadCount": `+raw+`}`)
+
+		if repo.Downloads() != 1234 {
+			t.Errorf("Expected 1234 downloads from %s, got %d", raw, repo.Downloads())
+		}
+	}
+}
+
+func TestDownloadCountSupportsLargeValues(t *testing.T) {
+	repo := decodeRepository(t, `{"DownloadCount": 9007199254740993}`)
+
+	if repo.Downloads() != 9007199254740993 {
+		t.Errorf("Expected large download count to be exact, got %d", repo.Downloads())
+	}
+}
+
+func TestTimestampAcceptsSecondsAndMilliseconds(t *testing.T) {
+	expected := time.Unix(1700000000, 0)
+
+	for _, raw := range []string{`1700000000`, `1700000000000`, `"1700000000"`, `"1700000000000"`, `1.7e9`} {
+		repo := decodeRepository(t, `{"LastUpdate": `+raw+`}`)
+
+		if !repo.LastUpdate.Equal(expected) {
+			t.Errorf("Expected %v from %s, got %v", expected, raw, repo.LastUpdate.Time)
+		}
+	}
+}
+
+func TestTimestampAcceptsRFC3339Strings(t *testing.T) {
+	repo := decodeRepository(t, `{"LastUpdated": "2023-11-14T22:13:20Z"}`)
+
+	if repo.LastUpdated.Unix() != 1700000000 {
+		t.Errorf("Expected 1700000000, got %d", repo.LastUpdated.Unix())
+	}
+}
+
+func TestTimestampTreatsZeroAsMissing(t *testing.T) {
+	for _, raw := range []string{`0`, `"0"`, `null`, `""`} {
+		repo := decodeRepository(t, `{"LastUpdate": `+raw+`}`)
+
+		if !repo.LastUpdate.IsZero() {
+			t.Errorf("Expected missing timestamp from %s, got %v", raw, repo.LastUpdate.Time)
+		}
+	}
+}
+
+func TestVersionAcceptsStringsAndNumbers(t *testing.T) {
+	for raw, expected := range map[string]Version{
+		`"1.2.3.4"`: "1.2.3.4",
+		`1.2`:       "1.2",
+		`3`:         "3",
+		`null`:      "",
+	} {
+		repo := decodeRepository(t, `{"AssemblyVersion": `+raw+`}`)
+
+		if repo.AssemblyVersion != expected {
+			t.Errorf("Expected version %q from %s, got %q", expected, raw, repo.AssemblyVersion)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	cases := []struct {
+		left, right Version
+		expected    int
+	}{
+		{"1.2.3.4", "1.2.3.4", 0},
+		{"1.2.3.10", "1.2.3.9", 1},
+		{"1.2", "1.2.0.0", 0},
+		{"v2.0.0", "1.9.9.9", 1},
+		{"1.0.0-beta", "1.0.1", -1},
+		{"", "0.0.0.1", -1},
+	}
+
+	for _, c := range cases {
+		if result := c.left.Compare(c.right); result != c.expected {
+			t.Errorf("Expected %q compared to %q to be %d, got %d", c.left, c.right, c.expected, result)
+		}
+	}
+}
+
+func TestFlagAcceptsBooleansStringsAndNumbers(t *testing.T) {
+	for raw, expected := range map[string]Flag{
+		`true`:    true,
+		`"True"`:  true,
+		`1`:       true,
+		`false`:   false,
+		`"false"`: false,
+		`0`:       false,
+		`null`:    false,
+	} {
+		repo := decodeRepository(t, `{"IsHide": `+raw+`}`)
+
+		if (repo.IsHide != nil && bool(*repo.IsHide)) != bool(expected) {
+			t.Errorf("Expected %v from %s, got %v", expected, raw, repo.IsHide)
+		}
+	}
+}
+
+func TestRepositoryEncodesDalamudWireShape(t *testing.T) {
+	repo := decodeRepository(t, `{
+		"Author": "Senither",
+		"Name": "Example",
+		"InternalName": "Example",
+		"AssemblyVersion": "1.0.0.0",
+		"DalamudApiLevel": "12",
+		"DownloadCount": "42",
+		"LastUpdate": 1700000000000,
+		"IsHide": "false",
+		"IsTestingExclusive": 0,
+		"Tags": null
+	}`)
+
+	content, err := json.Marshal(repo)
+	if err != nil {
+		t.Fatalf("Expected repository to encode, got %v", err)
+	}
+
+	var encoded map[string]any
+	if err := json.Unmarshal(content, &encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"AssemblyVersion":    "1.0.0.0",
+		"DalamudApiLevel":    float64(12),
+		"DownloadCount":      float64(42),
+		"LastUpdate":         float64(1700000000),
+		"IsHide":             false,
+		"IsTestingExclusive": false,
+	}
+
+	for key, value := range expected {
+		if encoded[key] != value {
+			t.Errorf("Expected %s to be encoded as %v, got %v", key, value, encoded[key])
+		}
+	}
+
+	for _, key := range []string{"LastUpdated", "TestingAssemblyVersion", "TestingDalamudApiLevel"} {
+		if _, exists := encoded[key]; exists {
+			t.Errorf("Expected %s to be omitted, got %v", key, encoded[key])
+		}
+	}
+
+	var decoded Repository
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("Expected encoded repository to decode, got %v", err)
+	}
+
+	if decoded.LastUpdate.Unix() != repo.LastUpdate.Unix() || decoded.Downloads() != repo.Downloads() {
+		t.Errorf("Expected encoding to be lossless, got %+v", decoded)
+	}
+}
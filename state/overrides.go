@@ -0,0 +1,31 @@
+package state
+
+import (
+	"slices"
+	"time"
+)
+
+// repositoryOverride patches a plugin entry before it's served, it's guarded
+// by repositoriesMu and set by the config since the state can't import it.
+var repositoryOverride = func(repo Repository) (Repository, bool) { return repo, false }
+
+// SetRepositoryOverride sets the function used to patch plugin entries before
+// they're served, the entries in the state itself are never changed.
+func SetRepositoryOverride(override func(repo Repository) (Repository, bool)) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	repositoryOverride = override
+	repositoryLastUpdatedAt = time.Now().Unix()
+}
+
+// servedRepository returns the entry as it's served, with the pinned entry
+// from the history in its place and the overrides applied.
+func servedRepository(repo Repository) Repository {
+	repo = applyPin(repo)
+	repo.Tags = slices.Clone(repo.Tags)
+
+	repo, _ = repositoryOverride(repo)
+
+	return repo
+}
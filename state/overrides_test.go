@@ -0,0 +1,28 @@
+package state
+
+import "testing"
+
+func TestTouchingServedRepositoryKeepsOverridesOutOfTheState(t *testing.T) {
+	useNopStore(t)
+	t.Cleanup(func() { repositoryOverride = func(repo Repository) (Repository, bool) { return repo, false } })
+
+	UpsertRepository(conflictingRepository("https://example.com/repo.json", "1.0.0.0"))
+
+	SetRepositoryOverride(func(repo Repository) (Repository, bool) {
+		repo.Name = "Overridden"
+		return repo, true
+	})
+
+	served := GetRepositories()
+	if served[0].Name != "Overridden" {
+		t.Fatalf("Expected the override to be served, got %q", served[0].Name)
+	}
+
+	TouchRepository(served[0])
+
+	SetRepositoryOverride(func(repo Repository) (Repository, bool) { return repo, false })
+
+	if name := GetRepositories()[0].Name; name == "Overridden" {
+		t.Errorf("Expected the override to be gone once removed, got %q", name)
+	}
+}
@@ -1,35 +1,146 @@
 package state
 
 import (
+	"slices"
 	"strings"
+	"sync"
 )
 
 type InternalPlugin struct {
-	Name    string
-	Private bool
+	Name     string
+	Private  bool
+	Provider string
+	Host     string
+
+	// ManifestAsset and ReleaseAsset are optional glob patterns used to find
+	// the manifest and plugin archive in a release.
+	ManifestAsset string
+	ReleaseAsset  string
+	// StableOnly skips drafts and pre-releases when picking the latest release.
+	StableOnly bool
 }
 
-var internalPlugins []InternalPlugin
+// Providers that internal plugins can be published on, GitHub is used when
+// an entry in plugins.txt doesn't use a "provider:" prefix.
+const (
+	ProviderGitHub  = "github"
+	ProviderGitLab  = "gitlab"
+	ProviderGitea   = "gitea"
+	ProviderForgejo = "forgejo"
+)
 
-func AddInternalPluginUrl(repoName string) {
-	var private = false
+var defaultProviderHosts = map[string]string{
+	ProviderGitHub:  "github.com",
+	ProviderGitLab:  "gitlab.com",
+	ProviderGitea:   "gitea.com",
+	ProviderForgejo: "codeberg.org",
+}
+
+// RepositoryUrl returns the web URL for the repository the plugin is
+// released from.
+func (ip InternalPlugin) RepositoryUrl() string {
+	return "https://" + ip.Host + "/" + ip.Name
+}
+
+var (
+	internalPluginsMu sync.RWMutex
+	internalPlugins   []InternalPlugin
+)
 
-	if strings.HasPrefix(repoName, "P:") {
-		private = true
-		repoName = strings.TrimPrefix(repoName, "P:")
+// AddInternalPluginUrl adds an internal plugin from an entry in plugins.txt,
+// entries are "owner/repo" GitHub names that can be prefixed with "P:" for
+// private repositories and a provider for other forges, for example
+// "gitlab:group/project" or "P:forgejo:codeberg.org/owner/repo".
+func AddInternalPluginUrl(repoName string) {
+	ip, ok := ParseInternalPlugin(repoName)
+	if !ok {
+		return
 	}
 
-	if pluginExists(repoName) || len(repoName) < 4 || !strings.Contains(repoName, "/") {
+	internalPluginsMu.Lock()
+	defer internalPluginsMu.Unlock()
+
+	if pluginExists(ip.Name) {
 		return
 	}
 
-	internalPlugins = append(internalPlugins, InternalPlugin{
-		Name:    repoName,
-		Private: private,
+	internalPlugins = append(internalPlugins, ip)
+}
+
+// AddInternalPlugin adds the plugin, or updates its settings if it exists.
+func AddInternalPlugin(ip InternalPlugin) {
+	internalPluginsMu.Lock()
+	defer internalPluginsMu.Unlock()
+
+	for i, existing := range internalPlugins {
+		if strings.EqualFold(existing.Name, ip.Name) {
+			internalPlugins[i] = ip
+			return
+		}
+	}
+
+	internalPlugins = append(internalPlugins, ip)
+}
+
+// RemoveInternalPlugin removes the plugin along with its release metadata
+// and the repository entry that was built from its releases.
+func RemoveInternalPlugin(repoName string) {
+	repository := GetRepositoryByGitHubReleaseRepositoryName(repoName)
+	if repository != nil {
+		DeleteRepository(*repository)
+	}
+
+	DeleteReleaseMetadata(repoName)
+	DeletePluginStatus(repoName)
+
+	internalPluginsMu.Lock()
+	defer internalPluginsMu.Unlock()
+
+	internalPlugins = slices.DeleteFunc(internalPlugins, func(ip InternalPlugin) bool {
+		return strings.EqualFold(ip.Name, repoName)
 	})
 }
 
+func ParseInternalPlugin(entry string) (InternalPlugin, bool) {
+	ip := InternalPlugin{Provider: ProviderGitHub}
+
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, "P:") {
+		ip.Private = true
+		entry = strings.TrimPrefix(entry, "P:")
+	}
+
+	if provider, rest, ok := strings.Cut(entry, ":"); ok {
+		if _, known := defaultProviderHosts[strings.ToLower(provider)]; !known {
+			return ip, false
+		}
+
+		ip.Provider = strings.ToLower(provider)
+		entry = rest
+	}
+
+	ip.Host = defaultProviderHosts[ip.Provider]
+
+	// Self-hosted instances are written with the host as the first segment,
+	// which is told apart from an owner or group by containing a dot.
+	if host, rest, ok := strings.Cut(entry, "/"); ok && ip.Provider != ProviderGitHub && strings.Contains(host, ".") {
+		ip.Host = strings.ToLower(host)
+		entry = rest
+	}
+
+	ip.Name = strings.Trim(entry, "/")
+
+	if len(ip.Name) < 4 || !strings.Contains(ip.Name, "/") {
+		return ip, false
+	}
+
+	return ip, true
+}
+
 func GetInternalPluginByName(repoName string) *InternalPlugin {
+	internalPluginsMu.RLock()
+	defer internalPluginsMu.RUnlock()
+
 	for _, repo := range internalPlugins {
 		if strings.EqualFold(repo.Name, repoName) {
 			return &repo
@@ -39,8 +150,24 @@ func GetInternalPluginByName(repoName string) *InternalPlugin {
 	return nil
 }
 
+func GetInternalPluginByRepositoryUrl(url string) *InternalPlugin {
+	internalPluginsMu.RLock()
+	defer internalPluginsMu.RUnlock()
+
+	for _, repo := range internalPlugins {
+		if strings.EqualFold(repo.RepositoryUrl(), url) {
+			return &repo
+		}
+	}
+
+	return nil
+}
+
 func GetInternalPlugins() []InternalPlugin {
-	return internalPlugins
+	internalPluginsMu.RLock()
+	defer internalPluginsMu.RUnlock()
+
+	return slices.Clone(internalPlugins)
 }
 
 func GetInternalPluginSize() int {
@@ -48,6 +175,9 @@ func GetInternalPluginSize() int {
 }
 
 func GetSenitherPluginSize() int {
+	internalPluginsMu.RLock()
+	defer internalPluginsMu.RUnlock()
+
 	counter := 0
 
 	for _, repo := range internalPlugins {
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// BlockedRepository is a plugin entry that is left out of the listing by the
+// policy, along with the reason it was blocked.
+type BlockedRepository struct {
+	Repository Repository
+	Reason     string
+}
+
+// blocked holds the entries denied by the policy, they're kept in memory so
+// operators can see them and so they can be served again if the policy
+// changes. It's guarded by repositoriesMu like the repositories slice.
+var (
+	blocked          []BlockedRepository
+	repositoryPolicy = func(repo Repository) (bool, string) { return true, "" }
+)
+
+// SetRepositoryPolicy sets the function that decides if a plugin entry is
+// served, the entries already in the state are evaluated again right away.
+// Internal plugins are always served.
+func SetRepositoryPolicy(policy func(repo Repository) (bool, string)) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	repositoryPolicy = policy
+
+	changed := false
+
+	for _, repo := range append(slices.Clone(repositories), shadowed...) {
+		if allowed, reason := evaluatePolicy(repo); !allowed {
+			deleteRepository(repo)
+			blockRepository(repo, reason)
+			changed = true
+		}
+	}
+
+	for _, entry := range slices.Clone(blocked) {
+		if allowed, _ := evaluatePolicy(entry.Repository); allowed {
+			unblockRepository(entry.Repository)
+			setRepository(entry.Repository, true)
+			changed = true
+		}
+	}
+
+	if changed {
+		repositoryLastUpdatedAt = time.Now().Unix()
+	}
+}
+
+// GetBlockedRepositories returns every entry that is blocked by the policy.
+func GetBlockedRepositories() []BlockedRepository {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	result := make([]BlockedRepository, len(blocked))
+	for i, entry := range blocked {
+		entry.Repository.Tags = slices.Clone(entry.Repository.Tags)
+		result[i] = entry
+	}
+
+	return result
+}
+
+// admitRepository serves the entry if the policy allows it, otherwise the
+// entry from the same source is removed and the entry is kept as blocked.
+func admitRepository(repo Repository, persist bool) bool {
+	allowed, reason := evaluatePolicy(repo)
+	if allowed {
+		unblockRepository(repo)
+		setRepository(repo, persist)
+
+		return true
+	}
+
+	if persist {
+		deleteRepository(repo)
+	}
+
+	blockRepository(repo, reason)
+
+	return false
+}
+
+func evaluatePolicy(repo Repository) (bool, string) {
+	if isInternalRepository(repo) {
+		return true, ""
+	}
+
+	return repositoryPolicy(repo)
+}
+
+func blockRepository(repo Repository, reason string) {
+	if i := slices.IndexFunc(blocked, func(entry BlockedRepository) bool {
+		return isSameEntry(entry.Repository, repo)
+	}); i != -1 {
+		blocked[i] = BlockedRepository{Repository: repo, Reason: reason}
+		return
+	}
+
+	blocked = append(blocked, BlockedRepository{Repository: repo, Reason: reason})
+
+	slog.Info("Plugin is blocked by the policy",
+		"internalName", repo.InternalName,
+		"url", repo.RepositoryOrigin.RepositoryUrl,
+		"reason", reason,
+	)
+}
+
+func unblockRepository(repo Repository) bool {
+	count := len(blocked)
+
+	blocked = slices.DeleteFunc(blocked, func(entry BlockedRepository) bool {
+		return isSameEntry(entry.Repository, repo)
+	})
+
+	return len(blocked) != count
+}
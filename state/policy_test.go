@@ -0,0 +1,41 @@
+package state
+
+import "testing"
+
+func TestPolicyBlocksEntriesAndPromotesShadowedEntries(t *testing.T) {
+	useNopStore(t)
+	t.Cleanup(func() { repositoryPolicy = func(Repository) (bool, string) { return true, "" } })
+
+	UpsertRepository(conflictingRepository("https://copycat.example.com", "2.0.0.0"))
+	UpsertRepository(conflictingRepository("https://official.example.com", "1.0.0.0"))
+
+	SetRepositoryPolicy(func(repo Repository) (bool, string) {
+		if repo.RepositoryOrigin.RepositoryUrl == "https://copycat.example.com" {
+			return false, "Copycat"
+		}
+
+		return true, ""
+	})
+
+	served := GetRepositories()
+	if len(served) != 1 || served[0].RepositoryOrigin.RepositoryUrl != "https://official.example.com" {
+		t.Fatalf("Expected the official entry to be served once the copycat is blocked, got %+v", served)
+	}
+
+	UpsertRepository(conflictingRepository("https://copycat.example.com", "3.0.0.0"))
+
+	blocked := GetBlockedRepositories()
+	if len(blocked) != 1 || blocked[0].Reason != "Copycat" || blocked[0].Repository.AssemblyVersion != "3.0.0.0" {
+		t.Fatalf("Expected the updated copycat entry to stay blocked, got %+v", blocked)
+	}
+
+	SetRepositoryPolicy(func(Repository) (bool, string) { return true, "" })
+
+	if served := GetRepositories(); served[0].RepositoryOrigin.RepositoryUrl != "https://copycat.example.com" {
+		t.Errorf("Expected the copycat entry to be served again once allowed, got %+v", served)
+	}
+
+	if len(GetBlockedRepositories()) != 0 {
+		t.Errorf("Expected no blocked entries once allowed")
+	}
+}
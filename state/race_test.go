@@ -0,0 +1,133 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type nopStore struct{}
+
+func (nopStore) LoadRepositories() ([]Repository, error)              { return nil, nil }
+func (nopStore) SaveRepository(Repository) error                      { return nil }
+func (nopStore) DeleteRepository(Repository) error                    { return nil }
+func (nopStore) LoadReleaseContexts() ([]GitHubReleaseContext, error) { return nil, nil }
+func (nopStore) SaveReleaseContext(GitHubReleaseContext) error        { return nil }
+func (nopStore) DeleteReleaseContext(string) error                    { return nil }
+func (nopStore) LoadHistories() ([]PluginHistory, error)              { return nil, nil }
+func (nopStore) SaveHistory(PluginHistory) error                      { return nil }
+func (nopStore) DeleteHistory(string) error                           { return nil }
+func (nopStore) Close() error                                         { return nil }
+
+func useNopStore(t *testing.T) {
+	previous := store
+	store = nopStore{}
+
+	t.Cleanup(func() {
+		store = previous
+		repositories = nil
+		shadowed = nil
+		blocked = nil
+		histories = make(map[string]*PluginHistory)
+		releaseContexts = nil
+		internalPlugins = nil
+		urls = []string{}
+	})
+}
+
+func TestConcurrentRepositoryUpsertDeleteAndRead(t *testing.T) {
+	useNopStore(t)
+
+	var wg sync.WaitGroup
+
+	for writer := range 4 {
+		wg.Go(func() {
+			for i := range 200 {
+				repo := Repository{
+					Name:            fmt.Sprintf("Plugin %d", i%20),
+					Author:          fmt.Sprintf("Author %d", writer),
+					InternalName:    fmt.Sprintf("Plugin%d", i%20),
+					Tags:            []string{"UI", "Utility"},
+					DalamudApiLevel: ApiLevel(10 + i%3),
+					RepositoryOrigin: RepositoryOrigin{
+						RepositoryUrl: "https://example.com/repo.json",
+					},
+				}
+
+				UpsertRepository(repo)
+
+				if i%3 == 0 {
+					DeleteRepository(repo)
+				}
+			}
+		})
+	}
+
+	for range 4 {
+		wg.Go(func() {
+			for range 200 {
+				for _, repo := range GetRepositories() {
+					repo.Tags[0] = "Mutated"
+				}
+
+				GetRepositoriesSize()
+				GetRepositoryTags()
+				GetRepositoryAuthors()
+				GetRepositoriesByOriginUrl("https://example.com/repo.json")
+				GetLatestDalamudApiLevel()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	for _, repo := range GetRepositories() {
+		if repo.Tags[0] == "Mutated" {
+			t.Fatalf("Expected snapshots to be isolated from the shared state, got %+v", repo.Tags)
+		}
+	}
+}
+
+func TestConcurrentReleaseMetadataAndPlugins(t *testing.T) {
+	useNopStore(t)
+
+	var wg sync.WaitGroup
+
+	for i := range 4 {
+		wg.Go(func() {
+			repoName := fmt.Sprintf("Senither/Plugin%d", i)
+
+			AddInternalPluginUrl(repoName)
+			AddUrl(fmt.Sprintf("https://example.com/%d.json", i))
+
+			for j := range 100 {
+				UpsertReleaseMetadata(repoName, []GitHubPluginRelease{
+					{TagName: fmt.Sprintf("v%d", j)},
+				})
+			}
+		})
+
+		wg.Go(func() {
+			for range 100 {
+				GetInternalPlugins()
+				GetInternalPluginByName(fmt.Sprintf("Senither/Plugin%d", i))
+				GetSenitherPluginSize()
+				GetUrls()
+
+				if releases := GetReleaseMetadataByRepositoryName(fmt.Sprintf("Senither/Plugin%d", i)); releases != nil {
+					_ = releases.Releases[0].TagName
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if GetInternalPluginSize() != 4 {
+		t.Errorf("Expected 4 internal plugins, got %d", GetInternalPluginSize())
+	}
+
+	if GetUrlsSize() != 4 {
+		t.Errorf("Expected 4 urls, got %d", GetUrlsSize())
+	}
+}
@@ -1,13 +1,15 @@
 package state
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"path"
 	"reflect"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 )
 
 type GitHubReleaseContext struct {
@@ -16,6 +18,7 @@ type GitHubReleaseContext struct {
 }
 
 type GitHubPluginRelease struct {
+	Id         int64  `json:"id,omitempty"`
 	Url        string `json:"url"`
 	TagName    string `json:"tag_name"`
 	Draft      bool   `json:"draft"`
@@ -34,9 +37,8 @@ type GitHubPluginReleaseAsset struct {
 }
 
 var (
-	releaseContexts       []GitHubReleaseContext
-	releasesTimer         = time.NewTimer(time.Nanosecond)
-	releasesLastUpdatedAt = time.Now().Unix()
+	releaseContextsMu sync.RWMutex
+	releaseContexts   []GitHubReleaseContext
 )
 
 func UpsertReleaseMetadata(repoName string, releases []GitHubPluginRelease) bool {
@@ -46,10 +48,13 @@ func UpsertReleaseMetadata(repoName string, releases []GitHubPluginRelease) bool
 		return false
 	}
 
+	releaseContextsMu.Lock()
+	defer releaseContextsMu.Unlock()
+
 	var index = -1
 
 	for i, r := range releaseContexts {
-		if r.RepositoryName == repoName {
+		if strings.EqualFold(r.RepositoryName, repoName) {
 			index = i
 			break
 		}
@@ -61,7 +66,7 @@ func UpsertReleaseMetadata(repoName string, releases []GitHubPluginRelease) bool
 			Releases:       releases,
 		})
 
-		writePluginReleasesToDisk()
+		persistReleaseContext(releaseContexts[len(releaseContexts)-1])
 		return true
 	}
 
@@ -69,15 +74,112 @@ func UpsertReleaseMetadata(repoName string, releases []GitHubPluginRelease) bool
 		return false
 	}
 
+	// The persisted context is keyed by the name, so it's replaced if the
+	// name of the plugin is spelled differently now.
+	if previous := releaseContexts[index].RepositoryName; previous != ip.Name {
+		if err := store.DeleteReleaseContext(previous); err != nil {
+			slog.Error("Failed to delete persisted plugin releases",
+				"err", err,
+				"repoName", previous,
+			)
+		}
+	}
+
 	releaseContexts[index] = GitHubReleaseContext{
 		RepositoryName: ip.Name,
 		Releases:       releases,
 	}
 
-	writePluginReleasesToDisk()
+	persistReleaseContext(releaseContexts[index])
 	return true
 }
 
+// Release actions that can be applied to the release metadata of a plugin.
+const (
+	ReleasePublished = "published"
+	ReleaseEdited    = "edited"
+	ReleaseDeleted   = "deleted"
+)
+
+// ApplyReleaseEvent applies a single published, edited or deleted release to
+// the release metadata of the plugin and returns the updated releases. It
+// returns false if there's no metadata for the plugin yet, or if an edited
+// release isn't in the metadata, in which case the releases must be fetched
+// in full.
+func ApplyReleaseEvent(repoName string, action string, release GitHubPluginRelease) ([]GitHubPluginRelease, bool) {
+	releaseContextsMu.Lock()
+	defer releaseContextsMu.Unlock()
+
+	index := slices.IndexFunc(releaseContexts, func(r GitHubReleaseContext) bool {
+		return strings.EqualFold(r.RepositoryName, repoName)
+	})
+
+	if index == -1 {
+		return nil, false
+	}
+
+	releases := slices.Clone(releaseContexts[index].Releases)
+	existing := slices.IndexFunc(releases, func(r GitHubPluginRelease) bool {
+		if release.Id != 0 && r.Id != 0 {
+			return r.Id == release.Id
+		}
+
+		return r.TagName == release.TagName
+	})
+
+	switch action {
+	case ReleasePublished:
+		if existing == -1 {
+			// Releases are sorted newest first, so new releases go in front.
+			releases = slices.Insert(releases, 0, release)
+		} else {
+			releases[existing] = release
+		}
+
+	case ReleaseEdited:
+		// An edited release we don't know about can be older than the
+		// releases that were fetched, so its place in the list is unknown.
+		if existing == -1 {
+			return nil, false
+		}
+
+		releases[existing] = release
+
+	case ReleaseDeleted:
+		if existing != -1 {
+			releases = slices.Delete(releases, existing, existing+1)
+		}
+
+	default:
+		return nil, false
+	}
+
+	releaseContexts[index].Releases = releases
+	persistReleaseContext(releaseContexts[index])
+
+	return slices.Clone(releases), true
+}
+
+func DeleteReleaseMetadata(repoName string) {
+	releaseContextsMu.Lock()
+	defer releaseContextsMu.Unlock()
+
+	for i, r := range releaseContexts {
+		if strings.EqualFold(r.RepositoryName, repoName) {
+			releaseContexts = append(releaseContexts[:i], releaseContexts[i+1:]...)
+
+			if err := store.DeleteReleaseContext(r.RepositoryName); err != nil {
+				slog.Error("Failed to delete persisted plugin releases",
+					"err", err,
+					"repoName", r.RepositoryName,
+				)
+			}
+
+			return
+		}
+	}
+}
+
 func GetDownloadUrlForPrivatePlugin(repoName string, tag string, asset *GitHubPluginReleaseAsset) string {
 	url := strings.TrimSuffix(strings.TrimSpace(os.Getenv("APP_URL")), "/")
 
@@ -85,8 +187,12 @@ func GetDownloadUrlForPrivatePlugin(repoName string, tag string, asset *GitHubPl
 }
 
 func GetReleaseMetadataByRepositoryName(repoName string) *GitHubReleaseContext {
+	releaseContextsMu.RLock()
+	defer releaseContextsMu.RUnlock()
+
 	for _, r := range releaseContexts {
-		if r.RepositoryName == repoName {
+		if strings.EqualFold(r.RepositoryName, repoName) {
+			r.Releases = slices.Clone(r.Releases)
 			return &r
 		}
 	}
@@ -109,35 +215,69 @@ func GetManifestAndLatestReleaseAssets(release GitHubPluginRelease) (*GitHubPlug
 	return manifestAsset, latestAsset
 }
 
-func LoadCachedPluginReleasesDataFromDisk() {
-	content, err := os.ReadFile(cachePath("cached-plugin-releases.json"))
-	if err != nil {
-		return
+// LatestRelease returns the newest release the plugin should be served from,
+// drafts and pre-releases are skipped for plugins that only follow stable.
+func (ip InternalPlugin) LatestRelease(releases []GitHubPluginRelease) (GitHubPluginRelease, bool) {
+	for _, release := range releases {
+		if ip.StableOnly && (release.Draft || release.Prerelease) {
+			continue
+		}
+
+		return release, true
 	}
 
-	var repositories []GitHubReleaseContext
-	if err := json.Unmarshal(content, &repositories); err != nil {
-		log.Fatalf("Error converting JSON: %v", err)
+	return GitHubPluginRelease{}, false
+}
+
+// FindReleaseAssets returns the manifest and plugin archive in the release,
+// using the asset name patterns for the plugin if they're set.
+func (ip InternalPlugin) FindReleaseAssets(release GitHubPluginRelease) (*GitHubPluginReleaseAsset, *GitHubPluginReleaseAsset) {
+	manifestAsset, latestAsset := GetManifestAndLatestReleaseAssets(release)
+
+	if ip.ManifestAsset != "" {
+		manifestAsset = findAssetByPattern(release, ip.ManifestAsset)
 	}
 
-	for _, repo := range repositories {
-		UpsertReleaseMetadata(repo.RepositoryName, repo.Releases)
+	if ip.ReleaseAsset != "" {
+		latestAsset = findAssetByPattern(release, ip.ReleaseAsset)
 	}
+
+	return manifestAsset, latestAsset
 }
 
-func writePluginReleasesToDisk() {
-	releasesLastUpdatedAt = time.Now().Unix()
+func findAssetByPattern(release GitHubPluginRelease, pattern string) *GitHubPluginReleaseAsset {
+	for _, asset := range release.Assets {
+		if matched, _ := path.Match(pattern, asset.Name); matched {
+			return &asset
+		}
+	}
+
+	return nil
+}
 
-	if releasesTimer != nil {
-		releasesTimer.Stop()
+func LoadCachedPluginReleasesDataFromDisk() {
+	cached, err := store.LoadReleaseContexts()
+	if err != nil {
+		slog.Error("Failed to load cached plugin releases, starting without a cache", "err", err)
 	}
 
-	releasesTimer = time.AfterFunc(5*time.Second, func() {
-		content, err := json.Marshal(releaseContexts)
-		if err != nil {
-			log.Fatalf("Error converting to JSON: %v", err)
+	releaseContextsMu.Lock()
+	defer releaseContextsMu.Unlock()
+
+	for _, context := range cached {
+		if GetInternalPluginByName(context.RepositoryName) == nil {
+			continue
 		}
 
-		os.WriteFile(cachePath("cached-plugin-releases.json"), content, 0644)
-	})
+		releaseContexts = append(releaseContexts, context)
+	}
+}
+
+func persistReleaseContext(context GitHubReleaseContext) {
+	if err := store.SaveReleaseContext(context); err != nil {
+		slog.Error("Failed to persist plugin releases",
+			"err", err,
+			"repoName", context.RepositoryName,
+		)
+	}
 }
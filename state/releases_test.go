@@ -0,0 +1,61 @@
+package state
+
+import "testing"
+
+func TestApplyReleaseEvent(t *testing.T) {
+	useNopStore(t)
+
+	if _, ok := ApplyReleaseEvent("Senither/Plugin", ReleasePublished, GitHubPluginRelease{TagName: "v2"}); ok {
+		t.Fatalf("Expected the event to be skipped without release metadata")
+	}
+
+	releaseContexts = []GitHubReleaseContext{{
+		RepositoryName: "Senither/Plugin",
+		Releases:       []GitHubPluginRelease{{Id: 1, TagName: "v1"}},
+	}}
+
+	releases, ok := ApplyReleaseEvent("senither/plugin", ReleasePublished, GitHubPluginRelease{Id: 2, TagName: "v2"})
+	if !ok || len(releases) != 2 || releases[0].TagName != "v2" {
+		t.Fatalf("Expected the published release to be added first, got %+v", releases)
+	}
+
+	releases, _ = ApplyReleaseEvent("Senither/Plugin", ReleaseEdited, GitHubPluginRelease{Id: 1, TagName: "v1.0", Body: "Fixed"})
+	if len(releases) != 2 || releases[1].TagName != "v1.0" || releases[1].Body != "Fixed" {
+		t.Fatalf("Expected the edited release to be replaced in place, got %+v", releases)
+	}
+
+	if _, ok := ApplyReleaseEvent("Senither/Plugin", ReleaseEdited, GitHubPluginRelease{Id: 3, TagName: "v0.9"}); ok {
+		t.Fatalf("Expected an unknown edited release to require a full fetch")
+	}
+
+	releases, _ = ApplyReleaseEvent("Senither/Plugin", ReleaseDeleted, GitHubPluginRelease{Id: 2, TagName: "v2"})
+	if len(releases) != 1 || releases[0].Id != 1 {
+		t.Fatalf("Expected the deleted release to be removed, got %+v", releases)
+	}
+
+	if stored := GetReleaseMetadataByRepositoryName("Senither/Plugin"); stored == nil || len(stored.Releases) != 1 {
+		t.Errorf("Expected the release metadata to be updated, got %+v", stored)
+	}
+}
+
+func TestReleaseMetadataIsMatchedWithoutCase(t *testing.T) {
+	useNopStore(t)
+
+	internalPlugins = []InternalPlugin{{Name: "Senither/Plugin"}}
+	releaseContexts = []GitHubReleaseContext{{
+		RepositoryName: "Senither/Plugin",
+		Releases:       []GitHubPluginRelease{{Id: 1, TagName: "v1"}},
+	}}
+
+	if !UpsertReleaseMetadata("senither/plugin", []GitHubPluginRelease{{Id: 2, TagName: "v2"}}) {
+		t.Fatal("Expected the release metadata to be updated")
+	}
+
+	if len(releaseContexts) != 1 {
+		t.Fatalf("Expected the existing release metadata to be updated in place, got %+v", releaseContexts)
+	}
+
+	if stored := GetReleaseMetadataByRepositoryName("SENITHER/PLUGIN"); stored == nil || stored.Releases[0].TagName != "v2" {
+		t.Errorf("Expected the release metadata to be found without case, got %+v", stored)
+	}
+}
@@ -1,14 +1,14 @@
 package state
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
 	"os"
+	"slices"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,20 +19,20 @@ type Repository struct {
 	Description            string           `json:"Description"`
 	Changelog              *string          `json:"Changelog,omitempty"`
 	InternalName           string           `json:"InternalName"`
-	AssemblyVersion        interface{}      `json:"AssemblyVersion,omitempty"`
-	TestingAssemblyVersion interface{}      `json:"TestingAssemblyVersion,omitempty"`
+	AssemblyVersion        Version          `json:"AssemblyVersion,omitzero"`
+	TestingAssemblyVersion Version          `json:"TestingAssemblyVersion,omitzero"`
 	RepoUrl                *string          `json:"RepoUrl"`
 	IconUrl                *string          `json:"IconUrl,omitempty"`
 	ApplicableVersion      *string          `json:"ApplicableVersion,omitempty"`
 	Tags                   []string         `json:"Tags"`
-	DalamudApiLevel        interface{}      `json:"DalamudApiLevel,omitempty"`
+	DalamudApiLevel        ApiLevel         `json:"DalamudApiLevel,omitzero"`
 	IsOutdated             bool             `json:"IsOutdated"`
-	TestingDalamudApiLevel interface{}      `json:"TestingDalamudApiLevel,omitempty"`
-	IsHide                 *interface{}     `json:"IsHide,omitempty"`
-	IsTestingExclusive     *interface{}     `json:"IsTestingExclusive,omitempty"`
-	LastUpdated            interface{}      `json:"LastUpdated,omitempty"`
-	DownloadCount          interface{}      `json:"DownloadCount,omitempty"`
-	LastUpdate             interface{}      `json:"LastUpdate,omitempty"`
+	TestingDalamudApiLevel ApiLevel         `json:"TestingDalamudApiLevel,omitzero"`
+	IsHide                 *Flag            `json:"IsHide,omitempty"`
+	IsTestingExclusive     *Flag            `json:"IsTestingExclusive,omitempty"`
+	LastUpdated            Timestamp        `json:"LastUpdated,omitzero"`
+	DownloadCount          *Count           `json:"DownloadCount,omitempty"`
+	LastUpdate             Timestamp        `json:"LastUpdate,omitzero"`
 	LoadPriority           *int64           `json:"LoadPriority,omitempty"`
 	LoadRequiredState      *int64           `json:"LoadRequiredState,omitempty"`
 	LoadSync               *bool            `json:"LoadSync,omitempty"`
@@ -43,22 +43,119 @@ type Repository struct {
 	RepositoryOrigin       RepositoryOrigin `json:"OriginRepositoryUrl"`
 }
 
+// Downloads returns the download count of the plugin, or 0 if the source
+// didn't provide one.
+func (r Repository) Downloads() int64 {
+	if r.DownloadCount == nil {
+		return 0
+	}
+
+	return int64(*r.DownloadCount)
+}
+
 type RepositoryOrigin struct {
 	RepositoryUrl    string `json:"RepositoryUrl"`
 	LastUpdatedAt    int64  `json:"LastUpdatedAt"`
 	IsInternalPlugin *bool  `json:"IsInternalPlugin,omitempty"`
 	IsPrivatePlugin  *bool  `json:"IsPrivatePlugin,omitempty"`
+	IsDegraded       bool   `json:"IsDegraded,omitempty"`
 }
 
+// repositoriesMu guards the repositories slice and its last updated timestamp,
+// the getters only ever hand out copies so callers can't mutate shared state.
 var (
+	repositoriesMu          sync.RWMutex
 	repositories            []Repository
-	repositoryTimer         = time.NewTimer(time.Nanosecond)
 	repositoryLastUpdatedAt = time.Now().Unix()
+
+	// The latest API level is decided by the config first, then the upstream
+	// Dalamud release source, and finally the highest level of any plugin.
+	configuredDalamudApiLevel ApiLevel
+	upstreamDalamudApiLevel   ApiLevel
 )
 
+// TouchRepository refreshes the last updated timestamp of the stored entry
+// from the same source as the given entry. Only the InternalName and source
+// of the given entry are used, so a served copy with overrides or a pin is
+// never written back to the state.
 func TouchRepository(repo Repository) {
-	repo.RepositoryOrigin.LastUpdatedAt = time.Now().Unix()
-	UpsertRepository(repo)
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	index := getRepositoryIndex(repo)
+	if index == -1 || !isSameEntry(repositories[index], repo) {
+		return
+	}
+
+	repositories[index].RepositoryOrigin.LastUpdatedAt = time.Now().Unix()
+
+	saveRepository(repositories[index])
+	recordHistory(repositories[index])
+}
+
+// TouchRepositoriesByOriginUrl refreshes the last updated timestamp for every
+// repository from the given source without persisting them, the timestamp is
+// only used to expire repositories and decide if a source should be fetched
+// on startup, so losing it on restart just causes a single extra fetch.
+func TouchRepositoriesByOriginUrl(url string) int {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	now := time.Now().Unix()
+	touched := 0
+
+	for i, repository := range repositories {
+		if repository.RepositoryOrigin.RepositoryUrl == url {
+			repositories[i].RepositoryOrigin.LastUpdatedAt = now
+			touched++
+		}
+	}
+
+	for i, repository := range shadowed {
+		if repository.RepositoryOrigin.RepositoryUrl == url {
+			shadowed[i].RepositoryOrigin.LastUpdatedAt = now
+		}
+	}
+
+	return touched
+}
+
+// SetRepositoriesDegradedByOriginUrl marks every repository from the source as
+// degraded or not, degraded repositories are still served but their source is
+// failing. It returns the number of repositories that were changed.
+func SetRepositoriesDegradedByOriginUrl(url string, degraded bool) int {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	changed := 0
+
+	for i, repository := range repositories {
+		if repository.RepositoryOrigin.RepositoryUrl != url || repository.RepositoryOrigin.IsDegraded == degraded {
+			continue
+		}
+
+		repositories[i].RepositoryOrigin.IsDegraded = degraded
+		changed++
+
+		if err := store.SaveRepository(repositories[i]); err != nil {
+			slog.Error("Failed to persist repository",
+				"err", err,
+				"repository", repository.Name,
+			)
+		}
+	}
+
+	for i, repository := range shadowed {
+		if repository.RepositoryOrigin.RepositoryUrl == url {
+			shadowed[i].RepositoryOrigin.IsDegraded = degraded
+		}
+	}
+
+	if changed > 0 {
+		repositoryLastUpdatedAt = time.Now().Unix()
+	}
+
+	return changed
 }
 
 func UpsertRepository(repo Repository) {
@@ -66,52 +163,96 @@ func UpsertRepository(repo Repository) {
 		repo.RepoUrl = findRepositoryUrl(repo)
 	}
 
-	index := getRepositoryIndex(repo)
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
 
-	if index == -1 {
-		repositories = append(repositories, repo)
-	} else {
-		repositories[index] = repo
+	if admitRepository(repo, true) {
+		recordHistory(repo)
 	}
 
-	writeRepositoriesToDisk()
+	repositoryLastUpdatedAt = time.Now().Unix()
 }
 
+// DeleteRepository removes the entry from the same source as the repository,
+// if another source provides the same plugin its entry is served instead.
 func DeleteRepository(repo Repository) {
-	index := getRepositoryIndex(repo)
-	if index == -1 {
-		return
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	deleted := deleteRepository(repo)
+	if unblockRepository(repo) || deleted {
+		repositoryLastUpdatedAt = time.Now().Unix()
+	}
+}
+
+// DeleteRepositoriesByOriginUrl removes every repository that was loaded from
+// the given source URL and returns the number of repositories removed.
+func DeleteRepositoriesByOriginUrl(url string) int {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
+
+	for _, repository := range slices.Clone(shadowed) {
+		if repository.RepositoryOrigin.RepositoryUrl == url {
+			deleteRepository(repository)
+		}
+	}
+
+	blocked = slices.DeleteFunc(blocked, func(entry BlockedRepository) bool {
+		return entry.Repository.RepositoryOrigin.RepositoryUrl == url
+	})
+
+	deleted := 0
+
+	for _, repository := range slices.Clone(repositories) {
+		if repository.RepositoryOrigin.RepositoryUrl == url && deleteRepository(repository) {
+			deleted++
+		}
 	}
 
-	repositories = append(repositories[:index], repositories[index+1:]...)
+	if deleted > 0 {
+		repositoryLastUpdatedAt = time.Now().Unix()
+	}
 
-	writeRepositoriesToDisk()
+	return deleted
 }
 
+// GetRepositories returns a snapshot of all the repositories, the outdated
+// flag is computed on the copies so reads never write to the shared state.
 func GetRepositories() []Repository {
-	latestDalamudApiLevel := GetLatestDalamudApiLevel()
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
+	latestDalamudApiLevel := getLatestDalamudApiLevel()
 
+	snapshot := make([]Repository, len(repositories))
 	for i, repository := range repositories {
-		if repository.DalamudApiLevel != nil {
-			level, err := strconv.ParseFloat(fmt.Sprintf("%+v", repository.DalamudApiLevel), 64)
-			if err == nil {
-				repositories[i].IsOutdated = level != latestDalamudApiLevel
-			}
+		repository = servedRepository(repository)
+
+		if repository.DalamudApiLevel != 0 {
+			repository.IsOutdated = repository.DalamudApiLevel != latestDalamudApiLevel
 		}
+
+		snapshot[i] = repository
 	}
 
-	return repositories
+	return snapshot
 }
 
 func GetRepositoriesSize() int {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
 	return len(repositories)
 }
 
 func GetRepositoryTags() map[string]string {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
 	tags := make(map[string]string)
 
 	for _, repository := range repositories {
-		for _, tag := range repository.Tags {
+		for _, tag := range servedRepository(repository).Tags {
 			normalizedTag := strings.Trim(strings.TrimSpace(tag), "\"")
 			if normalizedTag == "" {
 				continue
@@ -142,10 +283,13 @@ func GetRepositoryTags() map[string]string {
 }
 
 func GetRepositoryAuthors() []string {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
 	authorMap := make(map[string]string)
 
 	for _, repository := range repositories {
-		for _, author := range strings.Split(repository.Author, ",") {
+		for _, author := range strings.Split(servedRepository(repository).Author, ",") {
 			normalizedAuthor := strings.TrimSpace(author)
 			if normalizedAuthor == "" {
 				continue
@@ -190,13 +334,21 @@ func GetRepositoryByGitHubReleaseRepositoryName(repoName string) *Repository {
 		repoName,
 	)
 
+	ip := GetInternalPluginByName(repoName)
+	if ip == nil {
+		return nil
+	}
+
 	for _, repository := range GetRepositories() {
-		ip := GetInternalPluginByName(repoName)
-		if ip == nil {
-			continue
+		if repository.RepositoryOrigin.IsInternalPlugin != nil && *repository.RepositoryOrigin.IsInternalPlugin &&
+			strings.EqualFold(repository.RepositoryOrigin.RepositoryUrl, ip.RepositoryUrl()) {
+			return &repository
 		}
 
 		downloadLink := getAvailableDownloadLink(repository)
+		if downloadLink == nil {
+			continue
+		}
 
 		if ip.Private && strings.Contains(*downloadLink, localLink) {
 			return &repository
@@ -219,81 +371,90 @@ func GetRepositoryByAuthorAndInternalName(author string, internalName string) *R
 }
 
 func GetRepositoriesLastUpdatedAt() int64 {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
+
 	return repositoryLastUpdatedAt
 }
 
 func LoadCachedRepositoryDataFromDisk() {
-	content, err := os.ReadFile(cachePath("cached-repositories.json"))
+	cached, err := store.LoadRepositories()
 	if err != nil {
-		return
+		slog.Error("Failed to load cached repositories, starting without a cache", "err", err)
 	}
 
-	var repositories []Repository
-	if err := json.Unmarshal(content, &repositories); err != nil {
-		log.Fatalf("Error converting JSON: %v", err)
-	}
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
 
-	for _, repo := range repositories {
-		UpsertRepository(repo)
+	for _, repo := range cached {
+		if repo.RepoUrl == nil || *repo.RepoUrl == "" {
+			repo.RepoUrl = findRepositoryUrl(repo)
+		}
+
+		admitRepository(repo, false)
 	}
+
+	loadCachedHistories()
 }
 
-func LoadRepositoriesFromDisk() {
-	content, err := os.ReadFile("repositories.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
+func GetLatestDalamudApiLevel() ApiLevel {
+	repositoriesMu.RLock()
+	defer repositoriesMu.RUnlock()
 
-	repositories := strings.Split(string(content), "\n")
+	return getLatestDalamudApiLevel()
+}
 
-	for _, repo := range repositories {
-		if repo == "" {
-			continue
-		}
+// SetConfiguredDalamudApiLevel sets the latest API level from the config, it
+// takes precedence over the upstream level and the levels of the plugins.
+func SetConfiguredDalamudApiLevel(level ApiLevel) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
 
-		AddUrl(strings.Trim(repo, "\r"))
+	if configuredDalamudApiLevel != level {
+		configuredDalamudApiLevel = level
+		repositoryLastUpdatedAt = time.Now().Unix()
 	}
 }
 
-func LoadPluginsFromDisk() {
-	content, err := os.ReadFile("plugins.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
+// SetUpstreamDalamudApiLevel sets the latest API level read from the upstream
+// Dalamud release source, zero falls back to the levels of the plugins.
+func SetUpstreamDalamudApiLevel(level ApiLevel) {
+	repositoriesMu.Lock()
+	defer repositoriesMu.Unlock()
 
-	plugins := strings.Split(string(content), "\n")
+	if upstreamDalamudApiLevel != level {
+		upstreamDalamudApiLevel = level
+		repositoryLastUpdatedAt = time.Now().Unix()
+	}
+}
 
-	for _, repo := range plugins {
-		if repo == "" {
-			continue
-		}
+func getLatestDalamudApiLevel() ApiLevel {
+	if configuredDalamudApiLevel > 0 {
+		return configuredDalamudApiLevel
+	}
 
-		AddInternalPluginUrl(strings.Trim(repo, "\r"))
+	if upstreamDalamudApiLevel > 0 {
+		return upstreamDalamudApiLevel
 	}
-}
 
-func GetLatestDalamudApiLevel() float64 {
-	var latestDalamudApiLevel float64
+	var latestDalamudApiLevel ApiLevel
 
 	for _, repo := range repositories {
-		if repo.DalamudApiLevel == nil {
-			continue
-		}
-
-		level, ok := repo.DalamudApiLevel.(float64)
-		if ok && level > latestDalamudApiLevel {
-			latestDalamudApiLevel = level
+		if repo.DalamudApiLevel > latestDalamudApiLevel {
+			latestDalamudApiLevel = repo.DalamudApiLevel
 		}
 	}
 
 	return latestDalamudApiLevel
 }
 
+// getRepositoryIndex returns the index of the served entry that conflicts with
+// the given entry, or -1 if there is none.
 func getRepositoryIndex(repo Repository) int {
+	key := conflictKey(repo)
+
 	for i, repository := range repositories {
-		if repository.Name == repo.Name &&
-			repository.Author == repo.Author &&
-			repository.InternalName == repo.InternalName {
+		if conflictKey(repository) == key {
 			return i
 		}
 	}
@@ -341,20 +502,3 @@ func getAvailableDownloadLink(repo Repository) *string {
 
 	return nil
 }
-
-func writeRepositoriesToDisk() {
-	repositoryLastUpdatedAt = time.Now().Unix()
-
-	if repositoryTimer != nil {
-		repositoryTimer.Stop()
-	}
-
-	repositoryTimer = time.AfterFunc(5*time.Second, func() {
-		content, err := json.Marshal(repositories)
-		if err != nil {
-			log.Fatalf("Error converting to JSON: %v", err)
-		}
-
-		os.WriteFile(cachePath("cached-repositories.json"), content, 0644)
-	})
-}
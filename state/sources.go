@@ -0,0 +1,237 @@
+package state
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states for a source, an open circuit means the source is
+// skipped until its backoff expires, after which a single trial fetch is made
+// while the circuit is half-open.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// FetchStatus is the outcome of the most recent fetches for a source or an
+// internal plugin.
+type FetchStatus struct {
+	LastFetchedAt       time.Time
+	LastSuccessAt       time.Time
+	LastError           string
+	ConsecutiveFailures int
+	RetryAt             time.Time
+	CircuitOpen         bool
+
+	// The details of the most recent response, the warnings are the problems
+	// found while parsing the response that didn't fail the whole fetch.
+	HttpStatus   int
+	ResponseSize int64
+	Warnings     []string
+}
+
+// Circuit returns the circuit breaker state of the source.
+func (s FetchStatus) Circuit() string {
+	switch {
+	case !s.CircuitOpen:
+		return CircuitClosed
+	case time.Now().Before(s.RetryAt):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// InBackoff returns true if the source shouldn't be fetched yet.
+func (s FetchStatus) InBackoff() bool {
+	return time.Now().Before(s.RetryAt)
+}
+
+// SourceStatus holds the fetch metadata for a repository source URL, the
+// validators are sent back to the source so unchanged repositories can be
+// answered with a 304 Not Modified response.
+type SourceStatus struct {
+	FetchStatus
+
+	Url          string
+	ETag         string
+	LastModified string
+	Validation   []ValidationResult
+}
+
+// ValidationIssue is a problem a validation rule found with a plugin entry.
+type ValidationIssue struct {
+	Rule     string
+	Severity string
+	Message  string
+}
+
+// ValidationResult holds the issues found with a plugin entry from a source,
+// the index is the position of the entry in the source response.
+type ValidationResult struct {
+	Index        int
+	InternalName string
+	Rejected     bool
+	Issues       []ValidationIssue
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]SourceStatus)
+
+	pluginStatusesMu sync.RWMutex
+	pluginStatuses   = make(map[string]FetchStatus)
+)
+
+func GetSourceStatus(url string) SourceStatus {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	status, ok := sources[url]
+	if !ok {
+		return SourceStatus{Url: url}
+	}
+
+	return status
+}
+
+func SetSourceValidators(url string, etag string, lastModified string) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	status := sources[url]
+	status.Url = url
+	status.ETag = etag
+	status.LastModified = lastModified
+
+	sources[url] = status
+}
+
+// ClearSourceValidators drops the validators for the source, so the next
+// fetch downloads the whole repository again.
+func ClearSourceValidators(url string) {
+	SetSourceValidators(url, "", "")
+}
+
+// RecordSourceFetch records the outcome of a fetch for the source, a nil
+// error marks the fetch as successful and resets the failure count.
+func RecordSourceFetch(url string, err error) SourceStatus {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	status := sources[url]
+	status.Url = url
+	status.FetchStatus = status.FetchStatus.record(err)
+
+	sources[url] = status
+
+	return status
+}
+
+// SetSourceBackoff sets when the source may be fetched again and whether its
+// circuit breaker is open.
+func SetSourceBackoff(url string, retryAt time.Time, circuitOpen bool) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	status := sources[url]
+	status.Url = url
+	status.RetryAt = retryAt
+	status.CircuitOpen = circuitOpen
+
+	sources[url] = status
+}
+
+// SetSourceResponse records the details of the most recent response from the
+// source.
+func SetSourceResponse(url string, httpStatus int, size int64, warnings []string) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	status := sources[url]
+	status.Url = url
+	status.HttpStatus = httpStatus
+	status.ResponseSize = size
+	status.Warnings = warnings
+
+	sources[url] = status
+}
+
+// SetSourceValidation records the validation results for the plugin entries
+// from the most recent response from the source.
+func SetSourceValidation(url string, results []ValidationResult) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	status := sources[url]
+	status.Url = url
+	status.Validation = results
+
+	sources[url] = status
+}
+
+func DeleteSourceStatus(url string) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	delete(sources, url)
+}
+
+func GetPluginStatus(repoName string) FetchStatus {
+	pluginStatusesMu.RLock()
+	defer pluginStatusesMu.RUnlock()
+
+	return pluginStatuses[strings.ToLower(repoName)]
+}
+
+// RecordPluginFetch records the outcome of a release fetch for the internal
+// plugin, a nil error marks the fetch as successful.
+func RecordPluginFetch(repoName string, err error) {
+	pluginStatusesMu.Lock()
+	defer pluginStatusesMu.Unlock()
+
+	key := strings.ToLower(repoName)
+	pluginStatuses[key] = pluginStatuses[key].record(err)
+}
+
+// SetPluginResponse records the details of the most recent manifest response
+// for the internal plugin.
+func SetPluginResponse(repoName string, httpStatus int, size int64) {
+	pluginStatusesMu.Lock()
+	defer pluginStatusesMu.Unlock()
+
+	key := strings.ToLower(repoName)
+
+	status := pluginStatuses[key]
+	status.HttpStatus = httpStatus
+	status.ResponseSize = size
+
+	pluginStatuses[key] = status
+}
+
+func DeletePluginStatus(repoName string) {
+	pluginStatusesMu.Lock()
+	defer pluginStatusesMu.Unlock()
+
+	delete(pluginStatuses, strings.ToLower(repoName))
+}
+
+func (s FetchStatus) record(err error) FetchStatus {
+	s.LastFetchedAt = time.Now()
+
+	if err != nil {
+		s.LastError = err.Error()
+		s.ConsecutiveFailures++
+		return s
+	}
+
+	s.LastSuccessAt = s.LastFetchedAt
+	s.LastError = ""
+	s.ConsecutiveFailures = 0
+	s.RetryAt = time.Time{}
+	s.CircuitOpen = false
+
+	return s
+}
@@ -0,0 +1,155 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	repositoriesBucket = []byte("repositories")
+	releasesBucket     = []byte("releases")
+	historyBucket      = []byte("history")
+)
+
+// boltStore persists every repository and release context as its own key in
+// an embedded bbolt database, so each upsert is a small transaction that is
+// durable as soon as it returns.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func newBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{repositoriesBucket, releasesBucket, historyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) LoadRepositories() ([]Repository, error) {
+	var repos []Repository
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(repositoriesBucket).ForEach(func(_, value []byte) error {
+			var repo Repository
+			if err := json.Unmarshal(value, &repo); err != nil {
+				return err
+			}
+
+			repos = append(repos, repo)
+			return nil
+		})
+	})
+
+	return repos, err
+}
+
+func (s *boltStore) SaveRepository(repo Repository) error {
+	content, err := json.Marshal(repo)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(repositoriesBucket).Put([]byte(repositoryKey(repo)), content)
+	})
+}
+
+func (s *boltStore) DeleteRepository(repo Repository) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(repositoriesBucket).Delete([]byte(repositoryKey(repo)))
+	})
+}
+
+func (s *boltStore) LoadReleaseContexts() ([]GitHubReleaseContext, error) {
+	var contexts []GitHubReleaseContext
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(releasesBucket).ForEach(func(_, value []byte) error {
+			var context GitHubReleaseContext
+			if err := json.Unmarshal(value, &context); err != nil {
+				return err
+			}
+
+			contexts = append(contexts, context)
+			return nil
+		})
+	})
+
+	return contexts, err
+}
+
+func (s *boltStore) SaveReleaseContext(context GitHubReleaseContext) error {
+	content, err := json.Marshal(context)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(releasesBucket).Put([]byte(context.RepositoryName), content)
+	})
+}
+
+func (s *boltStore) DeleteReleaseContext(repoName string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(releasesBucket).Delete([]byte(repoName))
+	})
+}
+
+func (s *boltStore) LoadHistories() ([]PluginHistory, error) {
+	var histories []PluginHistory
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).ForEach(func(_, value []byte) error {
+			var history PluginHistory
+			if err := json.Unmarshal(value, &history); err != nil {
+				return err
+			}
+
+			histories = append(histories, history)
+			return nil
+		})
+	})
+
+	return histories, err
+}
+
+func (s *boltStore) SaveHistory(history PluginHistory) error {
+	content, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).Put([]byte(strings.ToLower(history.InternalName)), content)
+	})
+}
+
+func (s *boltStore) DeleteHistory(internalName string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).Delete([]byte(strings.ToLower(internalName)))
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,240 @@
+package state
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	repositoriesCacheFile = "cached-repositories.json"
+	releasesCacheFile     = "cached-plugin-releases.json"
+	historyCacheFile      = "cached-plugin-history.json"
+)
+
+// jsonStore keeps a copy of the state in memory and rewrites the whole cache
+// file five seconds after the last change, anything changed within that
+// window is lost if the process crashes before the write happens. The files
+// themselves are replaced atomically, see writeCacheFile.
+type jsonStore struct {
+	mu              sync.Mutex
+	repositories    []Repository
+	releaseContexts []GitHubReleaseContext
+	histories       []PluginHistory
+	repositoryTimer *time.Timer
+	releasesTimer   *time.Timer
+	historyTimer    *time.Timer
+}
+
+func newJsonStore() *jsonStore {
+	return &jsonStore{}
+}
+
+func (s *jsonStore) LoadRepositories() ([]Repository, error) {
+	var repos []Repository
+	if err := readCacheFile(cachePath(repositoriesCacheFile), &repos); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.repositories = append([]Repository(nil), repos...)
+	s.mu.Unlock()
+
+	return repos, nil
+}
+
+func (s *jsonStore) SaveRepository(repo Repository) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := repositoryKey(repo)
+	for i, existing := range s.repositories {
+		if repositoryKey(existing) == key {
+			s.repositories[i] = repo
+			s.scheduleRepositoriesWrite()
+			return nil
+		}
+	}
+
+	s.repositories = append(s.repositories, repo)
+	s.scheduleRepositoriesWrite()
+
+	return nil
+}
+
+func (s *jsonStore) DeleteRepository(repo Repository) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := repositoryKey(repo)
+	for i, existing := range s.repositories {
+		if repositoryKey(existing) == key {
+			s.repositories = append(s.repositories[:i], s.repositories[i+1:]...)
+			s.scheduleRepositoriesWrite()
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonStore) LoadReleaseContexts() ([]GitHubReleaseContext, error) {
+	var contexts []GitHubReleaseContext
+	if err := readCacheFile(cachePath(releasesCacheFile), &contexts); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.releaseContexts = append([]GitHubReleaseContext(nil), contexts...)
+	s.mu.Unlock()
+
+	return contexts, nil
+}
+
+func (s *jsonStore) SaveReleaseContext(context GitHubReleaseContext) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.releaseContexts {
+		if existing.RepositoryName == context.RepositoryName {
+			s.releaseContexts[i] = context
+			s.scheduleReleasesWrite()
+			return nil
+		}
+	}
+
+	s.releaseContexts = append(s.releaseContexts, context)
+	s.scheduleReleasesWrite()
+
+	return nil
+}
+
+func (s *jsonStore) DeleteReleaseContext(repoName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.releaseContexts {
+		if existing.RepositoryName == repoName {
+			s.releaseContexts = append(s.releaseContexts[:i], s.releaseContexts[i+1:]...)
+			s.scheduleReleasesWrite()
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonStore) LoadHistories() ([]PluginHistory, error) {
+	var histories []PluginHistory
+	if err := readCacheFile(cachePath(historyCacheFile), &histories); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.histories = append([]PluginHistory(nil), histories...)
+	s.mu.Unlock()
+
+	return histories, nil
+}
+
+func (s *jsonStore) SaveHistory(history PluginHistory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.histories {
+		if strings.EqualFold(existing.InternalName, history.InternalName) {
+			s.histories[i] = history
+			s.scheduleHistoryWrite()
+			return nil
+		}
+	}
+
+	s.histories = append(s.histories, history)
+	s.scheduleHistoryWrite()
+
+	return nil
+}
+
+func (s *jsonStore) DeleteHistory(internalName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.histories {
+		if strings.EqualFold(existing.InternalName, internalName) {
+			s.histories = append(s.histories[:i], s.histories[i+1:]...)
+			s.scheduleHistoryWrite()
+			break
+		}
+	}
+
+	return nil
+}
+
+// Close flushes any pending writes to disk immediately.
+func (s *jsonStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+
+	if s.repositoryTimer != nil && s.repositoryTimer.Stop() {
+		errs = append(errs, writeCacheFile(cachePath(repositoriesCacheFile), s.repositories))
+	}
+
+	if s.releasesTimer != nil && s.releasesTimer.Stop() {
+		errs = append(errs, writeCacheFile(cachePath(releasesCacheFile), s.releaseContexts))
+	}
+
+	if s.historyTimer != nil && s.historyTimer.Stop() {
+		errs = append(errs, writeCacheFile(cachePath(historyCacheFile), s.histories))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *jsonStore) scheduleRepositoriesWrite() {
+	if s.repositoryTimer != nil {
+		s.repositoryTimer.Stop()
+	}
+
+	s.repositoryTimer = time.AfterFunc(5*time.Second, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := writeCacheFile(cachePath(repositoriesCacheFile), s.repositories); err != nil {
+			slog.Error("Failed to write repositories to disk", "err", err)
+		}
+	})
+}
+
+func (s *jsonStore) scheduleReleasesWrite() {
+	if s.releasesTimer != nil {
+		s.releasesTimer.Stop()
+	}
+
+	s.releasesTimer = time.AfterFunc(5*time.Second, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := writeCacheFile(cachePath(releasesCacheFile), s.releaseContexts); err != nil {
+			slog.Error("Failed to write plugin releases to disk", "err", err)
+		}
+	})
+}
+
+func (s *jsonStore) scheduleHistoryWrite() {
+	if s.historyTimer != nil {
+		s.historyTimer.Stop()
+	}
+
+	s.historyTimer = time.AfterFunc(5*time.Second, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := writeCacheFile(cachePath(historyCacheFile), s.histories); err != nil {
+			slog.Error("Failed to write plugin history to disk", "err", err)
+		}
+	})
+}
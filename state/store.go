@@ -0,0 +1,116 @@
+package state
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const storeDriverEnv = "APP_STORE"
+
+// Store persists the repository and release state so it survives restarts.
+type Store interface {
+	LoadRepositories() ([]Repository, error)
+	SaveRepository(repo Repository) error
+	DeleteRepository(repo Repository) error
+
+	LoadReleaseContexts() ([]GitHubReleaseContext, error)
+	SaveReleaseContext(context GitHubReleaseContext) error
+	DeleteReleaseContext(repoName string) error
+
+	LoadHistories() ([]PluginHistory, error)
+	SaveHistory(history PluginHistory) error
+	DeleteHistory(internalName string) error
+
+	Close() error
+}
+
+var store Store = newJsonStore()
+
+// OpenStore selects the persistence backend using the APP_STORE environment
+// variable, "json" (the default) keeps the cached JSON files while "bolt"
+// uses an embedded bbolt database stored in the cache directory.
+func OpenStore() error {
+	driver := strings.ToLower(strings.TrimSpace(os.Getenv(storeDriverEnv)))
+
+	switch driver {
+	case "", "json":
+		store = newJsonStore()
+
+	case "bolt", "bbolt":
+		boltStore, err := newBoltStore(cachePath("cache.db"))
+		if err != nil {
+			return err
+		}
+
+		if err := importJsonStore(boltStore); err != nil {
+			boltStore.Close()
+			return err
+		}
+
+		store = boltStore
+
+	default:
+		return fmt.Errorf("unknown store driver %q, expected json or bolt", driver)
+	}
+
+	slog.Info("Opened state store", "driver", driver)
+
+	return nil
+}
+
+func CloseStore() error {
+	return store.Close()
+}
+
+// importJsonStore copies the cached JSON files into an empty store, this lets
+// an existing installation switch backends without losing its cached data.
+func importJsonStore(target Store) error {
+	existing, err := target.LoadRepositories()
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+
+	source := newJsonStore()
+
+	// Corrupt cache files have already been logged by the loader, there is
+	// nothing worth importing from them so the store simply starts empty.
+	repos, _ := source.LoadRepositories()
+
+	for _, repo := range repos {
+		if err := target.SaveRepository(repo); err != nil {
+			return err
+		}
+	}
+
+	contexts, _ := source.LoadReleaseContexts()
+
+	for _, context := range contexts {
+		if err := target.SaveReleaseContext(context); err != nil {
+			return err
+		}
+	}
+
+	histories, _ := source.LoadHistories()
+
+	for _, history := range histories {
+		if err := target.SaveHistory(history); err != nil {
+			return err
+		}
+	}
+
+	if len(repos) > 0 || len(contexts) > 0 || len(histories) > 0 {
+		slog.Info("Imported cached JSON files into the state store",
+			"repositories", len(repos),
+			"releases", len(contexts),
+			"histories", len(histories),
+		)
+	}
+
+	return nil
+}
+
+func repositoryKey(repo Repository) string {
+	return repo.Name + "\x1f" + repo.Author + "\x1f" + repo.InternalName
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStorePersistsRepositories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	boltStore, err := newBoltStore(path)
+	if err != nil {
+		t.Fatalf("Expected store to open, got %v", err)
+	}
+
+	first := Repository{Name: "First", Author: "Senither", InternalName: "First"}
+	second := Repository{Name: "Second", Author: "Senither", InternalName: "Second"}
+
+	for _, repo := range []Repository{first, second, first} {
+		if err := boltStore.SaveRepository(repo); err != nil {
+			t.Fatalf("Expected repository to be saved, got %v", err)
+		}
+	}
+
+	if err := boltStore.DeleteRepository(second); err != nil {
+		t.Fatalf("Expected repository to be deleted, got %v", err)
+	}
+
+	boltStore.Close()
+
+	boltStore, err = newBoltStore(path)
+	if err != nil {
+		t.Fatalf("Expected store to reopen, got %v", err)
+	}
+	defer boltStore.Close()
+
+	repos, err := boltStore.LoadRepositories()
+	if err != nil {
+		t.Fatalf("Expected repositories to load, got %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].Name != first.Name {
+		t.Errorf("Expected only %s to be persisted, got %+v", first.Name, repos)
+	}
+}
+
+func TestRenamedRepositoryReplacesItsPersistedRecord(t *testing.T) {
+	useNopStore(t)
+
+	boltStore, err := newBoltStore(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("Expected store to open, got %v", err)
+	}
+	defer boltStore.Close()
+
+	store = boltStore
+
+	repo := conflictingRepository("https://example.com", "1.0.0.0")
+	UpsertRepository(repo)
+
+	repo.Name = "Renamed Plugin"
+	repo.Author = "New Author"
+	UpsertRepository(repo)
+
+	repos, err := boltStore.LoadRepositories()
+	if err != nil {
+		t.Fatalf("Expected repositories to load, got %v", err)
+	}
+
+	if len(repos) != 1 || repos[0].Name != repo.Name {
+		t.Errorf("Expected only the renamed record to be persisted, got %+v", repos)
+	}
+}
+
+func TestJsonStoreFlushesOnClose(t *testing.T) {
+	t.Setenv(cacheDirEnv, t.TempDir())
+
+	jsonStore := newJsonStore()
+	jsonStore.SaveRepository(Repository{Name: "First", Author: "Senither", InternalName: "First"})
+
+	if err := jsonStore.Close(); err != nil {
+		t.Fatalf("Expected pending writes to be flushed, got %v", err)
+	}
+
+	repos, err := newJsonStore().LoadRepositories()
+	if err != nil {
+		t.Fatalf("Expected repositories to load, got %v", err)
+	}
+
+	if len(repos) != 1 {
+		t.Errorf("Expected 1 repository, got %d", len(repos))
+	}
+}
+
+func TestCacheFileFallsBackToPreviousGeneration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	if err := writeCacheFile(path, []string{"first"}); err != nil {
+		t.Fatalf("Expected first generation to be written, got %v", err)
+	}
+
+	if err := writeCacheFile(path, []string{"second"}); err != nil {
+		t.Fatalf("Expected second generation to be written, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"sha256":"abc","data":["sec`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	if err := readCacheFile(path, &values); err != nil {
+		t.Fatalf("Expected backup generation to be loaded, got %v", err)
+	}
+
+	if len(values) != 1 || values[0] != "first" {
+		t.Errorf("Expected the first generation, got %v", values)
+	}
+}
+
+func TestCacheFileKeepsBackupWhenCurrentIsCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	for _, generation := range []string{"first", "second"} {
+		if err := writeCacheFile(path, []string{generation}); err != nil {
+			t.Fatalf("Expected the %s generation to be written, got %v", generation, err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"sha256":"abc","data":["sec`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeCacheFile(path, []string{"third"}); err != nil {
+		t.Fatalf("Expected the third generation to be written, got %v", err)
+	}
+
+	var values []string
+	if err := readVerifiedFile(path+backupSuffix, &values); err != nil || len(values) != 1 || values[0] != "first" {
+		t.Errorf("Expected the last good generation to be kept as the backup, got %v (%v)", values, err)
+	}
+}
+
+func TestCacheFileRejectsChecksumMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	if err := os.WriteFile(path, []byte(`{"sha256":"abc","data":["first"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	if err := readCacheFile(path, &values); err == nil {
+		t.Errorf("Expected checksum mismatch to be reported, got %v", values)
+	}
+}
+
+func TestCacheFileAcceptsLegacyJson(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	if err := os.WriteFile(path, []byte(`["first"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	if err := readCacheFile(path, &values); err != nil || len(values) != 1 {
+		t.Errorf("Expected legacy cache file to be loaded, got %v (%v)", values, err)
+	}
+}
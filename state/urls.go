@@ -4,25 +4,45 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 )
 
-var urls []string
+var (
+	urlsMu sync.RWMutex
+	urls   []string
+)
 
 func AddUrl(rawUrl string) {
+	urlsMu.Lock()
+	defer urlsMu.Unlock()
+
 	if urlExists(rawUrl) || len(rawUrl) < 4 {
 		return
 	}
 
-	if !isValidUrl(rawUrl) {
+	if !IsValidUrl(rawUrl) {
 		return
 	}
 
 	urls = append(urls, strings.Trim(rawUrl, "\r"))
 }
 
+func RemoveUrl(rawUrl string) {
+	urlsMu.Lock()
+	defer urlsMu.Unlock()
+
+	urls = slices.DeleteFunc(urls, func(url string) bool {
+		return url == rawUrl
+	})
+}
+
 func GetUrls() []string {
-	return urls
+	urlsMu.RLock()
+	defer urlsMu.RUnlock()
+
+	return slices.Clone(urls)
 }
 
 func GetUrlsSize() int {
@@ -39,7 +59,7 @@ func urlExists(rawUrl string) bool {
 	return false
 }
 
-func isValidUrl(rawUrl string) bool {
+func IsValidUrl(rawUrl string) bool {
 	url, err := url.ParseRequestURI(rawUrl)
 	if err != nil {
 		fmt.Println("Error parsing url: ", err)
@@ -37,3 +37,16 @@ func TestAddInvalidUrl(t *testing.T) {
 		t.Errorf("Expected 0 url, got %d", len(urls))
 	}
 }
+
+func TestRemoveUrl(t *testing.T) {
+	defer teardown()
+
+	AddUrl("https://example.com")
+	AddUrl("https://example.org")
+
+	RemoveUrl("https://example.com")
+
+	if len(urls) != 1 || urls[0] != "https://example.org" {
+		t.Errorf("Expected only https://example.org to remain, got %v", urls)
+	}
+}
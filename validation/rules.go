@@ -0,0 +1,178 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// maxApiLevel is well above any API level Dalamud has shipped, higher values
+// are almost always timestamps or typos in the manifest.
+const maxApiLevel = 100
+
+const maxDescriptionLength = 4000
+
+// iconExtensions are the image formats Dalamud is able to show as an icon.
+var iconExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp"}
+
+type rule struct {
+	name     string
+	severity string
+	check    func(index int, repo state.Repository, b batch) []string
+}
+
+var rules = []rule{
+	{name: "required_fields", severity: SeverityError, check: checkRequiredFields},
+	{name: "url_validity", severity: SeverityError, check: checkUrls},
+	{name: "api_level_range", severity: SeverityError, check: checkApiLevel},
+	{name: "duplicate_internal_name", severity: SeverityError, check: checkDuplicateInternalName},
+	{name: "icon_format", severity: SeverityWarning, check: checkIconFormat},
+	{name: "description_length", severity: SeverityWarning, check: checkDescriptionLength},
+}
+
+// batch holds what the rules need to know about the other entries from the
+// same source.
+type batch struct {
+	firstIndex map[string]int
+}
+
+func newBatch(repos []state.Repository) batch {
+	b := batch{firstIndex: make(map[string]int)}
+
+	for i, repo := range repos {
+		key := strings.ToLower(repo.InternalName)
+		if _, ok := b.firstIndex[key]; !ok && key != "" {
+			b.firstIndex[key] = i
+		}
+	}
+
+	return b
+}
+
+func checkRequiredFields(index int, repo state.Repository, b batch) []string {
+	var messages []string
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"InternalName", repo.InternalName},
+		{"Name", repo.Name},
+		{"Author", repo.Author},
+		{"AssemblyVersion", repo.AssemblyVersion.String()},
+	}
+
+	for _, field := range fields {
+		if strings.TrimSpace(field.value) == "" {
+			messages = append(messages, field.name+" is missing")
+		}
+	}
+
+	if isEmpty(repo.DownloadLinkInstall) {
+		messages = append(messages, "DownloadLinkInstall is missing")
+	}
+
+	return messages
+}
+
+func checkUrls(index int, repo state.Repository, b batch) []string {
+	var messages []string
+
+	links := []struct {
+		name  string
+		value *string
+	}{
+		{"DownloadLinkInstall", repo.DownloadLinkInstall},
+		{"DownloadLinkTesting", repo.DownloadLinkTesting},
+		{"DownloadLinkUpdate", repo.DownloadLinkUpdate},
+		{"RepoUrl", repo.RepoUrl},
+	}
+
+	for _, link := range links {
+		if isEmpty(link.value) {
+			continue
+		}
+
+		if !isHttpUrl(*link.value) {
+			messages = append(messages, fmt.Sprintf("%s is not a valid URL: %q", link.name, *link.value))
+		}
+	}
+
+	return messages
+}
+
+func checkApiLevel(index int, repo state.Repository, b batch) []string {
+	var messages []string
+
+	if repo.DalamudApiLevel < 1 || repo.DalamudApiLevel > maxApiLevel {
+		messages = append(messages, fmt.Sprintf("DalamudApiLevel %d is outside of the valid range 1-%d", repo.DalamudApiLevel, maxApiLevel))
+	}
+
+	if repo.TestingDalamudApiLevel != 0 && (repo.TestingDalamudApiLevel < 1 || repo.TestingDalamudApiLevel > maxApiLevel) {
+		messages = append(messages, fmt.Sprintf("TestingDalamudApiLevel %d is outside of the valid range 1-%d", repo.TestingDalamudApiLevel, maxApiLevel))
+	}
+
+	return messages
+}
+
+// checkDuplicateInternalName flags every entry after the first one that uses
+// the same InternalName, so the first entry in the source wins.
+func checkDuplicateInternalName(index int, repo state.Repository, b batch) []string {
+	first, ok := b.firstIndex[strings.ToLower(repo.InternalName)]
+	if !ok || first == index {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("InternalName %q is already used by entry #%d", repo.InternalName, first)}
+}
+
+func checkIconFormat(index int, repo state.Repository, b batch) []string {
+	if isEmpty(repo.IconUrl) {
+		return nil
+	}
+
+	parsed, err := url.Parse(*repo.IconUrl)
+	if err != nil || !isHttpUrl(*repo.IconUrl) {
+		return []string{fmt.Sprintf("IconUrl is not a valid URL: %q", *repo.IconUrl)}
+	}
+
+	// Icons served without an extension are common, so only a known extension
+	// that isn't an image format is reported.
+	extension := strings.ToLower(path.Ext(parsed.Path))
+	if extension != "" && !slices.Contains(iconExtensions, extension) {
+		return []string{fmt.Sprintf("IconUrl uses an unsupported image format %q", extension)}
+	}
+
+	return nil
+}
+
+func checkDescriptionLength(index int, repo state.Repository, b batch) []string {
+	length := utf8.RuneCountInString(strings.TrimSpace(repo.Description))
+
+	switch {
+	case length == 0 && (repo.Punchline == nil || strings.TrimSpace(*repo.Punchline) == ""):
+		return []string{"Description and Punchline are both missing"}
+	case length > maxDescriptionLength:
+		return []string{fmt.Sprintf("Description is %d characters long, the limit is %d", length, maxDescriptionLength)}
+	}
+
+	return nil
+}
+
+func isEmpty(value *string) bool {
+	return value == nil || strings.TrimSpace(*value) == ""
+}
+
+func isHttpUrl(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	return (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
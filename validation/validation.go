@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+// Severities a rule can report issues with, ordered from least to most severe.
+// A rule can also be turned off, and "none" can be used as the reject severity
+// to keep every plugin entry regardless of its issues.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+
+	SeverityOff = "off"
+	RejectNone  = "none"
+)
+
+var severities = []string{SeverityInfo, SeverityWarning, SeverityError}
+
+// Options changes how the plugin entries are validated.
+type Options struct {
+	// Reject is the lowest severity that rejects a plugin entry.
+	Reject string
+	// Severities overrides the default severity of a rule, keyed by rule name.
+	Severities map[string]string
+}
+
+// IsSeverity returns true if the value is a severity a rule can report.
+func IsSeverity(value string) bool {
+	return slices.Contains(severities, value)
+}
+
+// IsRule returns true if a rule exists with the given name.
+func IsRule(name string) bool {
+	return slices.ContainsFunc(rules, func(r rule) bool {
+		return r.name == name
+	})
+}
+
+// RuleNames returns the names of every rule.
+func RuleNames() []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.name
+	}
+
+	return names
+}
+
+// Validate runs every rule on the plugin entries from a source, returning the
+// entries that weren't rejected along with a result for every entry that had
+// at least one issue.
+func Validate(repos []state.Repository, opts Options) ([]state.Repository, []state.ValidationResult) {
+	b := newBatch(repos)
+
+	accepted := make([]state.Repository, 0, len(repos))
+	var results []state.ValidationResult
+
+	for i, repo := range repos {
+		result := state.ValidationResult{
+			Index:        i,
+			InternalName: repo.InternalName,
+		}
+
+		for _, r := range rules {
+			severity := r.severity
+			if override, ok := opts.Severities[r.name]; ok {
+				severity = override
+			}
+
+			if severity == SeverityOff {
+				continue
+			}
+
+			for _, message := range r.check(i, repo, b) {
+				result.Issues = append(result.Issues, state.ValidationIssue{
+					Rule:     r.name,
+					Severity: severity,
+					Message:  message,
+				})
+
+				if Rejects(severity, opts.Reject) {
+					result.Rejected = true
+				}
+			}
+		}
+
+		if len(result.Issues) > 0 {
+			results = append(results, result)
+		}
+
+		if !result.Rejected {
+			accepted = append(accepted, repo)
+		}
+	}
+
+	return accepted, results
+}
+
+// Summary describes the issues in a single line, used when logging results.
+func Summary(result state.ValidationResult) string {
+	messages := make([]string, len(result.Issues))
+	for i, issue := range result.Issues {
+		messages[i] = fmt.Sprintf("%s (%s): %s", issue.Rule, issue.Severity, issue.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Rejects returns true if an issue with the severity rejects a plugin entry
+// when using the reject severity.
+func Rejects(severity string, reject string) bool {
+	threshold := slices.Index(severities, reject)
+	if threshold == -1 {
+		return false
+	}
+
+	return slices.Index(severities, severity) >= threshold
+}
@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/senither/dalamud-plugin-listing/state"
+)
+
+func validRepository(internalName string) state.Repository {
+	downloadLink := "https://example.com/" + internalName + "/latest.zip"
+	iconUrl := "https://example.com/" + internalName + "/icon.png"
+
+	return state.Repository{
+		Author:              "Senither",
+		Name:                internalName,
+		Description:         "A plugin used in tests.",
+		InternalName:        internalName,
+		AssemblyVersion:     "1.0.0.0",
+		DalamudApiLevel:     12,
+		IconUrl:             &iconUrl,
+		DownloadLinkInstall: &downloadLink,
+	}
+}
+
+func TestValidateRejectsBySeverity(t *testing.T) {
+	broken := validRepository("Broken")
+	broken.DalamudApiLevel = 1700000000
+
+	icon := "https://example.com/icon.bmp"
+	warned := validRepository("Warned")
+	warned.IconUrl = &icon
+
+	repos := []state.Repository{validRepository("Valid"), broken, warned, validRepository("valid")}
+
+	accepted, results := Validate(repos, Options{Reject: SeverityError})
+
+	if len(accepted) != 2 || accepted[0].InternalName != "Valid" || accepted[1].InternalName != "Warned" {
+		t.Fatalf("Expected the broken and duplicated entries to be rejected, got %+v", accepted)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 entries with issues, got %+v", results)
+	}
+
+	expected := []struct {
+		index    int
+		rule     string
+		rejected bool
+	}{
+		{1, "api_level_range", true},
+		{2, "icon_format", false},
+		{3, "duplicate_internal_name", true},
+	}
+
+	for i, want := range expected {
+		result := results[i]
+		if result.Index != want.index || result.Rejected != want.rejected || result.Issues[0].Rule != want.rule {
+			t.Errorf("Expected entry #%d to fail %s (rejected: %t), got %+v", want.index, want.rule, want.rejected, result)
+		}
+	}
+}
+
+func TestValidateSeverityOverrides(t *testing.T) {
+	repo := validRepository("NoDescription")
+	repo.Description = ""
+	repo.DownloadLinkInstall = nil
+
+	accepted, results := Validate([]state.Repository{repo}, Options{
+		Reject: SeverityWarning,
+		Severities: map[string]string{
+			"required_fields":    SeverityInfo,
+			"description_length": SeverityOff,
+		},
+	})
+
+	if len(accepted) != 1 {
+		t.Fatalf("Expected the entry to be kept with lowered severities, got %+v", results)
+	}
+
+	if len(results) != 1 || len(results[0].Issues) != 1 || results[0].Issues[0].Severity != SeverityInfo {
+		t.Errorf("Expected a single info issue, got %+v", results)
+	}
+
+	if _, results := Validate([]state.Repository{repo}, Options{Reject: RejectNone}); results[0].Rejected {
+		t.Error("Expected nothing to be rejected when rejecting is turned off")
+	}
+}